@@ -0,0 +1,214 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cocotyty/sqlhelper/internel"
+)
+
+// Balance 选择从库的策略
+type Balance int
+
+const (
+	RoundRobin    Balance = iota // 轮询
+	LeastInFlight                // 选择正在使用的连接最少的从库
+)
+
+// NewCluster 创建读写分离的SQLHelper
+// SELECT 查询发送到从库 写入 事务 带锁的查询(FOR UPDATE 等)与 RETURNING 插入发送到主库
+// 依赖会话状态的查询(如 LAST_INSERT_ID() lastval() SCOPE_IDENTITY())同样发送到主库
+// 使用WithPrimary标记的context的查询同样发送到主库 用于写后立即读的场景
+// 从库连续出现连接错误时被暂时摘除 所有从库都被摘除或没有从库时使用主库
+// 语句缓存只支持单个 *sql.DB 集群中WithStmtCache不生效
+func NewCluster(primary *sql.DB, replicas []*sql.DB, opts ...Option) SQLHelper {
+	s := &sqlHelper{
+		db:           newCluster(primary, replicas),
+		Scanner:      internel.GlobalScanner,
+		SQLGenerator: internel.GlobalSQLGenerator,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithReplicaBalance 设置选择从库的策略 默认为RoundRobin 只对NewCluster生效
+func WithReplicaBalance(balance Balance) Option {
+	return func(s *sqlHelper) {
+		if c, ok := s.db.(*cluster); ok {
+			c.balance = balance
+		}
+	}
+}
+
+// WithReplicaEjection 从库连续maxFailures次出现连接错误时摘除duration 默认为3次 30秒 只对NewCluster生效
+// unhealthy判断错误是否为连接错误 为nil时使用默认的判断 网络错误与 driver.ErrBadConn 等
+func WithReplicaEjection(maxFailures int, duration time.Duration, unhealthy func(err error) bool) Option {
+	return func(s *sqlHelper) {
+		c, ok := s.db.(*cluster)
+		if !ok {
+			return
+		}
+		if maxFailures > 0 {
+			c.maxFailures = int32(maxFailures)
+		}
+		if duration > 0 {
+			c.ejectDuration = duration
+		}
+		if unhealthy != nil {
+			c.unhealthy = unhealthy
+		}
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary 标记ctx中的查询使用主库
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	force, _ := ctx.Value(primaryKey{}).(bool)
+	return force
+}
+
+// cluster 按语句类型在主库与从库之间路由
+type cluster struct {
+	primary       *sql.DB
+	replicas      []*replica
+	balance       Balance
+	next          uint32
+	maxFailures   int32
+	ejectDuration time.Duration
+	unhealthy     func(err error) bool
+	now           func() time.Time
+}
+
+type replica struct {
+	db       *sql.DB
+	failures int32
+	locker   sync.Mutex
+	until    time.Time // 摘除到的时间
+}
+
+func newCluster(primary *sql.DB, replicas []*sql.DB) *cluster {
+	c := &cluster{
+		primary:       primary,
+		maxFailures:   3,
+		ejectDuration: 30 * time.Second,
+		unhealthy:     isConnError,
+		now:           time.Now,
+	}
+	for _, db := range replicas {
+		c.replicas = append(c.replicas, &replica{db: db})
+	}
+	return c
+}
+
+func (c *cluster) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+func (c *cluster) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if usePrimary(ctx) || !isReadQuery(query) {
+		return c.primary.QueryContext(ctx, query, args...)
+	}
+	r := c.pick()
+	if r == nil {
+		return c.primary.QueryContext(ctx, query, args...)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	c.report(r, err)
+	return rows, err
+}
+
+func (c *cluster) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.primary.BeginTx(ctx, opts)
+}
+
+// pick 选择一个未被摘除的从库 没有时返回nil
+func (c *cluster) pick() *replica {
+	n := len(c.replicas)
+	if n == 0 {
+		return nil
+	}
+	now := c.now()
+	if c.balance == LeastInFlight {
+		var best *replica
+		bestInUse := 0
+		for _, r := range c.replicas {
+			if r.ejected(now) {
+				continue
+			}
+			if inUse := r.db.Stats().InUse; best == nil || inUse < bestInUse {
+				best, bestInUse = r, inUse
+			}
+		}
+		return best
+	}
+	// 在uint32上取模 计数溢出后在32位平台上也不会得到负的下标
+	start := atomic.AddUint32(&c.next, 1)
+	for i := 0; i < n; i++ {
+		if r := c.replicas[(start+uint32(i))%uint32(n)]; !r.ejected(now) {
+			return r
+		}
+	}
+	return nil
+}
+
+// report 记录从库的执行结果 连续出现连接错误时摘除从库
+func (c *cluster) report(r *replica, err error) {
+	if err == nil || !c.unhealthy(err) {
+		atomic.StoreInt32(&r.failures, 0)
+		return
+	}
+	if atomic.AddInt32(&r.failures, 1) < c.maxFailures {
+		return
+	}
+	atomic.StoreInt32(&r.failures, 0)
+	r.locker.Lock()
+	r.until = c.now().Add(c.ejectDuration)
+	r.locker.Unlock()
+}
+
+func (r *replica) ejected(now time.Time) bool {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	return now.Before(r.until)
+}
+
+// primaryOnlyQuery 需要在主库执行的 SELECT
+// 加锁的查询 包括Postgres的 FOR NO KEY UPDATE 与 FOR KEY SHARE
+// 以及依赖当前会话或会修改数据的函数 在从库上会失败或得到错误的结果
+var primaryOnlyQuery = regexp.MustCompile(`(?i)\bFOR\s+(NO\s+KEY\s+)?UPDATE\b|\bFOR\s+(KEY\s+)?SHARE\b|\bLOCK\s+IN\s+SHARE\s+MODE\b|` +
+	`\b(LAST_INSERT_ID|FOUND_ROWS|ROW_COUNT|GET_LOCK|RELEASE_LOCK|LASTVAL|CURRVAL|NEXTVAL|SETVAL|SCOPE_IDENTITY|PG_(TRY_)?ADVISORY_\w+)\s*\(|@@IDENTITY\b`)
+
+// isReadQuery 是否为可以发送到从库的查询 只有不带锁且不依赖会话的 SELECT 语句
+func isReadQuery(query string) bool {
+	q := strings.TrimLeft(query, " \t\r\n(")
+	if len(q) < 6 || !strings.EqualFold(q[:6], "SELECT") {
+		return false
+	}
+	return !primaryOnlyQuery.MatchString(q)
+}
+
+// isConnError 是否为连接错误 context取消与SQL本身的错误不视为从库不健康
+func isConnError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
@@ -0,0 +1,157 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestCluster(t *testing.T) {
+	primary, primaryMock, _ := sqlmock.New()
+	replica1, replicaMock1, _ := sqlmock.New()
+	replica2, replicaMock2, _ := sqlmock.New()
+	helper := NewCluster(primary, []*sql.DB{replica1, replica2})
+	ctx := context.Background()
+	var id int64
+
+	// 读请求轮询从库
+	replicaMock2.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	replicaMock1.ExpectQuery("(?i)SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	if err := helper.QueryContext(ctx, &id, "SELECT id FROM a"); err != nil || id != 2 {
+		t.Fatal(id, err)
+	}
+	if err := helper.QueryContext(ctx, &id, " select id FROM a"); err != nil || id != 1 {
+		t.Fatal(id, err)
+	}
+
+	// 写入 带锁的查询 RETURNING 与标记为主库的查询使用主库
+	primaryMock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	primaryMock.ExpectQuery("INSERT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	primaryMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	if _, err := helper.UpdateContext(ctx, "UPDATE a SET b = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := helper.QueryContext(ctx, &id, "SELECT id FROM a FOR UPDATE"); err != nil || id != 3 {
+		t.Fatal(id, err)
+	}
+	if err := helper.QueryContext(ctx, &id, "INSERT INTO a (b) VALUES (1) RETURNING id"); err != nil || id != 4 {
+		t.Fatal(id, err)
+	}
+	if err := helper.QueryContext(WithPrimary(ctx), &id, "SELECT id FROM a"); err != nil || id != 5 {
+		t.Fatal(id, err)
+	}
+
+	// 事务使用主库
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	primaryMock.ExpectCommit()
+	err := helper.WithTx(ctx, nil, func(tx SQLHelper) error {
+		return tx.QueryContext(ctx, &id, "SELECT id FROM a")
+	})
+	if err != nil || id != 6 {
+		t.Fatal(id, err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock1, replicaMock2} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCluster_PickWraparound(t *testing.T) {
+	primary, _, _ := sqlmock.New()
+	replica1, _, _ := sqlmock.New()
+	replica2, _, _ := sqlmock.New()
+	helper := NewCluster(primary, []*sql.DB{replica1, replica2})
+	c := helper.(*sqlHelper).db.(*cluster)
+
+	// 计数超过 2^31 与 2^32 回绕时仍按顺序轮询
+	c.next = math.MaxUint32 - 1
+	for i, db := range []*sql.DB{replica2, replica1, replica2} {
+		if r := c.pick(); r == nil || r.db != db {
+			t.Fatal(i, r)
+		}
+	}
+	c.next = 1 << 31
+	if r := c.pick(); r == nil || r.db != replica2 {
+		t.Fatal(r)
+	}
+}
+
+func TestIsReadQuery(t *testing.T) {
+	table := []struct {
+		query string
+		read  bool
+	}{
+		{"SELECT id FROM a", true},
+		{"(SELECT id FROM a) UNION (SELECT id FROM b)", true},
+		{"SELECT id, format FROM a WHERE name = 'for'", true},
+		{"SELECT id FROM a FOR UPDATE", false},
+		{"select id from a for  update nowait", false},
+		{"SELECT id FROM a FOR SHARE", false},
+		{"SELECT id FROM a FOR NO KEY UPDATE", false},
+		{"SELECT id FROM a\nFOR KEY SHARE SKIP LOCKED", false},
+		{"SELECT id FROM a LOCK IN SHARE MODE", false},
+		{"SELECT LAST_INSERT_ID()", false},
+		{"select last_insert_id ()", false},
+		{"SELECT lastval()", false},
+		{"SELECT currval('a_id_seq')", false},
+		{"SELECT SCOPE_IDENTITY()", false},
+		{"SELECT @@IDENTITY", false},
+		{"SELECT pg_advisory_lock(1)", false},
+		{"UPDATE a SET b = 1", false},
+	}
+	for _, test := range table {
+		if isReadQuery(test.query) != test.read {
+			t.Fatal(test.query)
+		}
+	}
+}
+
+func TestCluster_Ejection(t *testing.T) {
+	primary, primaryMock, _ := sqlmock.New()
+	replica, replicaMock, _ := sqlmock.New()
+	helper := NewCluster(primary, []*sql.DB{replica}, WithReplicaEjection(2, time.Minute, nil))
+	c := helper.(*sqlHelper).db.(*cluster)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	var id int64
+
+	// SQL错误不会摘除从库
+	replicaMock.ExpectQuery("SELECT").WillReturnError(errors.New("syntax error"))
+	replicaMock.ExpectQuery("SELECT").WillReturnError(errors.New("syntax error"))
+	helper.QueryContext(ctx, &id, "SELECT id FROM a")
+	helper.QueryContext(ctx, &id, "SELECT id FROM a")
+	if c.replicas[0].ejected(now) {
+		t.Fatal("ejected on sql error")
+	}
+
+	// 连续两次连接错误后摘除 读请求回到主库
+	replicaMock.ExpectQuery("SELECT").WillReturnError(io.ErrUnexpectedEOF)
+	replicaMock.ExpectQuery("SELECT").WillReturnError(io.ErrUnexpectedEOF)
+	helper.QueryContext(ctx, &id, "SELECT id FROM a")
+	helper.QueryContext(ctx, &id, "SELECT id FROM a")
+	if !c.replicas[0].ejected(now) {
+		t.Fatal("not ejected")
+	}
+	primaryMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	if err := helper.QueryContext(ctx, &id, "SELECT id FROM a"); err != nil || id != 1 {
+		t.Fatal(id, err)
+	}
+
+	// 摘除时间过后恢复
+	now = now.Add(2 * time.Minute)
+	replicaMock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	if err := helper.QueryContext(ctx, &id, "SELECT id FROM a"); err != nil || id != 2 {
+		t.Fatal(id, err)
+	}
+}
@@ -0,0 +1,50 @@
+package sqlhelper
+
+import (
+	"errors"
+
+	"github.com/cocotyty/sqlhelper/internel"
+)
+
+var (
+	// ErrNestedTransaction 在事务中再次开启事务时返回
+	ErrNestedTransaction = errors.New("nested transaction is not supported")
+	// ErrUnsafeWhere 按条件更新或删除时where为空 防止误操作整张表
+	ErrUnsafeWhere = errors.New("forbidden operation: empty `where` param")
+	// ErrStaleObject 声明了版本列的对象按主键更新时没有更新任何行 对象已被其他人修改或删除
+	ErrStaleObject = errors.New("stale object: version mismatch or row not found")
+	// ErrNotSharded ShardedHelper操作的类型没有注册分片规则
+	ErrNotSharded = errors.New("no shard rule registered for the type")
+	// ErrNoPrimaryKey 按主键操作的对象没有声明主键
+	ErrNoPrimaryKey = internel.ErrNoPrimaryKey
+	// ErrNotAddressable 需要写回对象时传入的不是指针
+	ErrNotAddressable = internel.ErrNotAddressable
+	// ErrInvalidScanType 不支持的扫描目标 *ScanTypeError 同样满足 errors.Is
+	ErrInvalidScanType = internel.ErrInvalidScanType
+	// ErrUnsupported 方言不支持的操作
+	ErrUnsupported = internel.ErrUnsupported
+)
+
+type (
+	// ScanTypeError 不支持的扫描目标或对象类型
+	ScanTypeError = internel.ScanTypeError
+	// ColumnScanError 扫描某一行的某一列时出现的错误 包含行号 列名与目标属性
+	ColumnScanError = internel.ColumnScanError
+	// StrictScanError 严格模式下结果列与结构体属性不匹配
+	StrictScanError = internel.StrictScanError
+)
+
+// IsDuplicateKey 错误是否为唯一键或主键冲突 支持 MySQL Postgres SQLite SQL Server 的常用驱动 其他类型的错误不会被识别
+func IsDuplicateKey(err error) bool {
+	return internel.IsDuplicateKey(err)
+}
+
+// IsDeadlock 错误是否为死锁 通常可以重试整个事务
+func IsDeadlock(err error) bool {
+	return internel.IsDeadlock(err)
+}
+
+// IsForeignKeyViolation 错误是否为违反外键约束
+func IsForeignKeyViolation(err error) bool {
+	return internel.IsForeignKeyViolation(err)
+}
@@ -0,0 +1,68 @@
+package sqlhelper
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/cocotyty/sqlhelper/internel"
+)
+
+// scanPlans 泛型方法的扫描计划 键为扫描目标的类型 *T 或 *[]T 值为*scanPlanHolder
+var scanPlans sync.Map
+
+// scanPlanHolder 每个类型只创建一次的扫描计划 创建失败时plan为nil
+type scanPlanHolder struct {
+	once sync.Once
+	plan *internel.ScanPlan
+}
+
+// planned 为ptr附加按类型预先计算的扫描计划 计划使用全局扫描器创建 使用其他扫描器的SQLHelper会忽略该计划
+func planned(ptr interface{}) interface{} {
+	typ := reflect.TypeOf(ptr)
+	v, ok := scanPlans.Load(typ)
+	if !ok {
+		v, _ = scanPlans.LoadOrStore(typ, &scanPlanHolder{})
+	}
+	holder := v.(*scanPlanHolder)
+	holder.once.Do(func() {
+		holder.plan, _ = internel.GlobalScanner.Plan(ptr)
+	})
+	if holder.plan == nil {
+		return ptr
+	}
+	return &internel.Planned{Plan: holder.plan, Ptr: ptr}
+}
+
+// Query 执行查询并返回所有行 T为结构体或基础类型
+func Query[T any](ctx context.Context, helper SQLHelper, sqlstr string, args ...interface{}) ([]T, error) {
+	var list []T
+	err := helper.QueryContext(ctx, planned(&list), sqlstr, args...)
+	return list, err
+}
+
+// QueryOne 执行查询并返回第一行 没有结果时返回 sql.ErrNoRows
+func QueryOne[T any](ctx context.Context, helper SQLHelper, sqlstr string, args ...interface{}) (T, error) {
+	var row T
+	err := helper.QueryContext(ctx, planned(&row), sqlstr, args...)
+	return row, err
+}
+
+// Get 按主键查询一行 参数顺序与GetByID相同 没有结果时返回 sql.ErrNoRows
+func Get[T any](ctx context.Context, helper SQLHelper, id ...interface{}) (T, error) {
+	var row T
+	err := helper.GetByID(ctx, planned(&row), id...)
+	return row, err
+}
+
+// Insert 插入对象 自增主键会写回object 与InsertObject相同
+func Insert[T any](ctx context.Context, helper SQLHelper, object *T) (int64, error) {
+	return helper.InsertObject(ctx, object)
+}
+
+// SelectFrom 查询T对应的表 subSQL为 WHERE ORDER BY 等FROM之后的语句
+func SelectFrom[T any](ctx context.Context, helper SQLHelper, subSQL string, args ...interface{}) ([]T, error) {
+	var list []T
+	err := helper.SelectFrom(ctx, planned(&list), subSQL, args...)
+	return list, err
+}
@@ -0,0 +1,52 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestGeneric(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	users, err := Query[testUser](ctx, helper, "SELECT id, name FROM test_user")
+	if err != nil || len(users) != 2 || users[1].Name != "b" {
+		t.Fatal(users, err)
+	}
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"count"}))
+	if _, err = QueryOne[int64](ctx, helper, "SELECT COUNT(*) FROM test_user"); err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE `id` = ?")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	user, err := Get[testUser](ctx, helper, 1)
+	if err != nil || user.Name != "a" {
+		t.Fatal(user, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE name = ?")).WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	users, err = SelectFrom[testUser](ctx, helper, "WHERE name = ?", "a")
+	if err != nil || len(users) != 1 {
+		t.Fatal(users, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`name`) VALUES (?)")).WithArgs("c").WillReturnResult(sqlmock.NewResult(3, 1))
+	user = testUser{Name: "c"}
+	id, err := Insert(ctx, helper, &user)
+	if err != nil || id != 3 || user.ID != 3 {
+		t.Fatal(id, user, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
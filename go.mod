@@ -1 +1,5 @@
 module github.com/cocotyty/sqlhelper
+
+go 1.18
+
+require gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0
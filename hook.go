@@ -0,0 +1,116 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Op 执行语句的方式
+type Op string
+
+const (
+	OpQuery  Op = "query"  // 查询并扫描全部结果 After在扫描结束后调用
+	OpCursor Op = "cursor" // 返回游标的查询 After在获取到结果集后调用 不包含读取的时间
+	OpExec   Op = "exec"   // 执行语句
+)
+
+// Hook 语句执行前后的钩子 用于日志 统计与链路追踪
+// 多个Hook时 Before按注册顺序调用 After按相反顺序调用
+type Hook interface {
+	// Before 执行前调用 返回的context用于执行语句及之后的钩子
+	Before(ctx context.Context, op Op, sql string, args []interface{}) context.Context
+	// After 执行后调用 result只在OpExec时不为nil
+	After(ctx context.Context, op Op, sql string, args []interface{}, result sql.Result, err error, duration time.Duration)
+}
+
+// WithHooks 注册语句执行的钩子 事务中同样生效
+func WithHooks(hooks ...Hook) Option {
+	return func(s *sqlHelper) {
+		s.hooks = append(append([]Hook(nil), s.hooks...), hooks...)
+	}
+}
+
+func (s *sqlHelper) before(ctx context.Context, op Op, sqlstr string, args []interface{}) context.Context {
+	for _, hook := range s.hooks {
+		ctx = hook.Before(ctx, op, sqlstr, args)
+	}
+	return ctx
+}
+
+func (s *sqlHelper) after(ctx context.Context, op Op, sqlstr string, args []interface{}, result sql.Result, err error, start time.Time) {
+	duration := time.Since(start)
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		s.hooks[i].After(ctx, op, sqlstr, args, result, err, duration)
+	}
+}
+
+// SlowQueryHook 记录执行时间超过Threshold的语句
+type SlowQueryHook struct {
+	Threshold time.Duration
+	// Log 记录慢查询 为nil时使用标准库log输出 不包含参数
+	Log func(ctx context.Context, op Op, sql string, args []interface{}, duration time.Duration, err error)
+}
+
+func (h *SlowQueryHook) Before(ctx context.Context, op Op, sql string, args []interface{}) context.Context {
+	return ctx
+}
+
+func (h *SlowQueryHook) After(ctx context.Context, op Op, sql string, args []interface{}, result sql.Result, err error, duration time.Duration) {
+	if duration < h.Threshold {
+		return
+	}
+	if h.Log != nil {
+		h.Log(ctx, op, sql, args, duration, err)
+		return
+	}
+	log.Printf("sqlhelper: slow %s (%s): %s", op, duration, sql)
+}
+
+// Tracer 创建span的最小接口 可以适配 OpenTelemetry 等实现
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span 一次语句执行对应的span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// TracingHook 为每条语句创建名为 sql.<op> 的span
+// 属性包括 db.system db.statement db.rows_affected sql.ErrNoRows 不视为错误
+type TracingHook struct {
+	Tracer Tracer
+	System string // 数据库类型 如 mysql postgres
+}
+
+type spanKey struct{}
+
+func (h *TracingHook) Before(ctx context.Context, op Op, sql string, args []interface{}) context.Context {
+	ctx, span := h.Tracer.Start(ctx, "sql."+string(op))
+	if h.System != "" {
+		span.SetAttribute("db.system", h.System)
+	}
+	span.SetAttribute("db.statement", sql)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (h *TracingHook) After(ctx context.Context, op Op, sqlstr string, args []interface{}, result sql.Result, err error, duration time.Duration) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	if result != nil {
+		if num, e := result.RowsAffected(); e == nil {
+			span.SetAttribute("db.rows_affected", num)
+		}
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+	}
+	span.End()
+}
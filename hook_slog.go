@@ -0,0 +1,79 @@
+//go:build go1.21
+
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// SlogHook 使用 log/slog 记录每条语句 出错时使用Error级别 sql.ErrNoRows 不视为错误
+type SlogHook struct {
+	Logger *slog.Logger // 为nil时使用 slog.Default()
+	Level  slog.Level   // 成功时的日志级别
+	// Redact 记录前处理每个参数 用于隐藏密码 手机号等敏感数据 为nil时使用RedactAll
+	Redact func(arg interface{}) interface{}
+}
+
+// RedactAll 隐藏所有参数的值
+func RedactAll(arg interface{}) interface{} {
+	return "***"
+}
+
+// RedactStrings 隐藏字符串与 []byte 参数 保留数字 时间等其他类型
+func RedactStrings(arg interface{}) interface{} {
+	switch arg.(type) {
+	case string, []byte, *string:
+		return "***"
+	}
+	return arg
+}
+
+// RedactNone 原样记录所有参数
+func RedactNone(arg interface{}) interface{} {
+	return arg
+}
+
+func (h *SlogHook) Before(ctx context.Context, op Op, sql string, args []interface{}) context.Context {
+	return ctx
+}
+
+func (h *SlogHook) After(ctx context.Context, op Op, sqlstr string, args []interface{}, result sql.Result, err error, duration time.Duration) {
+	logger := h.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := h.Level
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		level = slog.LevelError
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	redact := h.Redact
+	if redact == nil {
+		redact = RedactAll
+	}
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		redacted[i] = redact(arg)
+	}
+	attrs := []slog.Attr{
+		slog.String("op", string(op)),
+		slog.String("sql", sqlstr),
+		slog.Any("args", redacted),
+		slog.Duration("duration", duration),
+	}
+	if result != nil {
+		if num, e := result.RowsAffected(); e == nil {
+			attrs = append(attrs, slog.Int64("rows_affected", num))
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "sqlhelper", attrs...)
+}
@@ -0,0 +1,29 @@
+//go:build go1.21
+
+package sqlhelper
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestSlogHook(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	helper := New(db, WithHooks(&SlogHook{Logger: logger, Level: slog.LevelInfo, Redact: RedactStrings}))
+
+	mock.ExpectExec("UPDATE").WithArgs("secret", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.UpdateContext(context.Background(), "UPDATE user SET password = ? WHERE id = ?", "secret", 7); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret") || !strings.Contains(out, "args=\"[*** 7]\"") || !strings.Contains(out, "rows_affected=1") {
+		t.Fatal(out)
+	}
+}
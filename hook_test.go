@@ -0,0 +1,110 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+type testHook struct {
+	name  string
+	calls *[]string
+}
+
+type testHookKey struct{}
+
+func (h *testHook) Before(ctx context.Context, op Op, sql string, args []interface{}) context.Context {
+	*h.calls = append(*h.calls, h.name+".before."+string(op))
+	return context.WithValue(ctx, testHookKey{}, h.name)
+}
+
+func (h *testHook) After(ctx context.Context, op Op, sql string, args []interface{}, result sql.Result, err error, duration time.Duration) {
+	*h.calls = append(*h.calls, h.name+".after."+string(op)+"."+ctx.Value(testHookKey{}).(string))
+}
+
+func TestSQLHelper_Hooks(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	var calls []string
+	helper := New(db, WithHooks(&testHook{"a", &calls}, &testHook{"b", &calls}))
+	ctx := context.Background()
+
+	mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	if _, err := helper.UpdateContext(ctx, "UPDATE a SET b = 1"); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := helper.QueryContext(ctx, &id, "SELECT id FROM a"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"a.before.exec", "b.before.exec", "b.after.exec.b", "a.after.exec.b",
+		"a.before.query", "b.before.query", "b.after.query.b", "a.after.query.b",
+	}
+	if len(calls) != len(expected) {
+		t.Fatal(calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatal(calls)
+		}
+	}
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) RecordError(err error)                      { s.err = err }
+func (s *testSpan) End()                                       { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestTracingAndSlowQueryHook(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	tracer := &testTracer{}
+	var slow []string
+	helper := New(db, WithHooks(&TracingHook{Tracer: tracer, System: "mysql"}, &SlowQueryHook{
+		Threshold: 0,
+		Log: func(ctx context.Context, op Op, sql string, args []interface{}, duration time.Duration, err error) {
+			slow = append(slow, sql)
+		},
+	}))
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM a")).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := helper.DeleteContext(ctx, "DELETE FROM a"); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := helper.QueryContext(ctx, &id, "SELECT id FROM a"); err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+	if len(tracer.spans) != 2 || len(slow) != 2 {
+		t.Fatal(tracer.spans, slow)
+	}
+	exec, query := tracer.spans[0], tracer.spans[1]
+	if exec.name != "sql.exec" || !exec.ended || exec.attrs["db.rows_affected"] != int64(3) || exec.attrs["db.system"] != "mysql" {
+		t.Fatal(exec)
+	}
+	if query.name != "sql.query" || !query.ended || query.err != nil || query.attrs["db.statement"] != "SELECT id FROM a" {
+		t.Fatal(query)
+	}
+}
@@ -37,9 +37,19 @@ func (RawTypeColumn) PointerOf(v reflect.Value) (val reflect.Value, err error) {
 
 // 表示结构体映射的查询返回字段
 type Field struct {
-	i     int          // 索引
-	typ   reflect.Type //类型
-	embed *Field
+	i       int          // 索引
+	typ     reflect.Type //类型
+	embed   *Field
+	options fieldOption // db标签中声明的选项 嵌入属性的选项与最终属性相同
+}
+
+// index 属性在结构体中的索引路径 用于按声明顺序排列属性
+func (p *Field) index() []int {
+	index := []int{p.i}
+	if p.embed != nil {
+		index = append(index, p.embed.index()...)
+	}
+	return index
 }
 
 var sqlScannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
@@ -76,6 +86,36 @@ func (p *Field) PointerOf(v reflect.Value) (val reflect.Value, err error) {
 	return f.Addr(), nil
 }
 
+// ValueOf 获取结构体v中该属性的值 与PointerOf不同 不会为nil指针创建值 嵌入的指针为nil时返回零值
+func (p *Field) ValueOf(v reflect.Value) (val reflect.Value, err error) {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		err = ErrInvalidScanType
+		return
+	}
+	f := v.Field(p.i)
+	if p.embed == nil {
+		return f, nil
+	}
+	if p.typ.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return reflect.Zero(p.embed.leaf().typ), nil
+		}
+		f = f.Elem()
+	}
+	return p.embed.ValueOf(f)
+}
+
+// leaf 嵌入属性路径上最终的属性
+func (p *Field) leaf() *Field {
+	for p.embed != nil {
+		p = p.embed
+	}
+	return p
+}
+
 type NullValue struct {
 	Value reflect.Value
 }
@@ -110,13 +150,17 @@ func Fields(typ reflect.Type, mapper Mapper) map[string]*Field {
 	num := typ.NumField()
 	for i := 0; i < num; i++ {
 		f := typ.Field(i)
+		if mapper(f.Name, f.Tag) == ignoredFieldName {
+			continue
+		}
 		if f.Anonymous {
 			next := Fields(f.Type, mapper)
 			for name, path := range next {
 				fields[name] = &Field{
-					i:     i,
-					typ:   f.Type,
-					embed: path,
+					i:       i,
+					typ:     f.Type,
+					embed:   path,
+					options: path.options,
 				}
 			}
 		}
@@ -125,7 +169,7 @@ func Fields(typ reflect.Type, mapper Mapper) map[string]*Field {
 			continue
 		}
 		fieldMapperName := mapper(f.Name, f.Tag)
-		fields[fieldMapperName] = &Field{i: i, typ: f.Type}
+		fields[fieldMapperName] = &Field{i: i, typ: f.Type, options: parseFieldOptions(f.Tag)}
 	}
 	return fields
 }
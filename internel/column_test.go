@@ -179,3 +179,26 @@ func TestRawTypeColumn_PointerOf(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestField_ValueOf(t *testing.T) {
+	f := Fields(reflect.TypeOf(&testA{}), func(name string, tag reflect.StructTag) string {
+		return name
+	})
+	// 指针属性返回指针本身 不创建NullValue
+	val, err := f["A5"].ValueOf(reflect.ValueOf(&testA{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Interface().(*time.Time) != nil {
+		t.Fatal(val.Interface())
+	}
+	// 嵌入的指针为nil时返回零值 不修改原对象
+	a := &testA{}
+	val, err = f["C1"].ValueOf(reflect.ValueOf(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Bool() != false || a.testC != nil {
+		t.Fatal(val, a.testC)
+	}
+}
@@ -0,0 +1,133 @@
+package internel
+
+import "reflect"
+
+// Cursor 逐行读取查询结果 不会将全部结果缓存在内存中
+// 如
+// cursor, err := scanner.Cursor(rows)
+// defer cursor.Close()
+//
+//	for cursor.Next() {
+//	    cursor.Scan(&row)
+//	}
+//
+// err = cursor.Err()
+type Cursor struct {
+	rows     SQLRows
+	scanner  *RowsScanner
+	columns  []string
+	dest     interface{}    // 上一次Scan的目标 目标不变时复用producer
+	producer ValuesProducer // 为dest创建的producer
+	row      int            // 当前行 从0开始
+	err      error
+	closed   bool
+}
+
+// Cursor 创建逐行读取rows的游标 调用方必须调用Close
+func (rs *RowsScanner) Cursor(rows SQLRows) (*Cursor, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &Cursor{
+		rows:    rows,
+		scanner: rs,
+		columns: columns,
+		row:     -1,
+	}, nil
+}
+
+// Columns 查询结果的列名
+func (c *Cursor) Columns() []string {
+	return c.columns
+}
+
+// Next 移动到下一行 没有更多的行或出现错误时返回false并自动关闭 错误通过Err获取
+func (c *Cursor) Next() bool {
+	if c.closed || c.err != nil {
+		return false
+	}
+	if c.rows.Next() {
+		c.row++
+		return true
+	}
+	c.err = c.rows.Err()
+	if err := c.Close(); c.err == nil {
+		c.err = err
+	}
+	return false
+}
+
+// Scan 将当前行写入ptr ptr必须为指向结构体 基础类型或 map[string]interface{} 的指针
+// 连续使用相同的ptr时复用同一个ValuesProducer
+func (c *Cursor) Scan(ptr interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.producer == nil || c.dest != ptr {
+		producer, oneLine, err := c.scanner.builder.build(ptr, c.columns, c.rows, c.scanner.strict)
+		if err != nil {
+			return err
+		}
+		if !oneLine {
+			return &ScanTypeError{Type: reflect.TypeOf(ptr)}
+		}
+		c.dest, c.producer = ptr, producer
+	}
+	if err := c.rows.Scan(c.producer.Values()...); err != nil {
+		c.err = wrapScanError(err, c.row, c.columns, c.producer)
+		c.Close()
+		return c.err
+	}
+	return nil
+}
+
+// Err 返回遍历过程中出现的错误 包括结果集中断与关闭时的错误
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close 关闭游标 可以多次调用
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rows.Close()
+}
+
+// Each 对每一行调用fn fn为 func(row *T) error 或 func(row *T) 形式的函数 T为结构体或基础类型
+// 所有行共用同一个row 每行扫描前row会被重置为零值 fn不应在返回后继续持有row
+// fn返回错误时停止遍历并返回该错误 遍历结束后关闭游标 fn的类型不符时返回 *ScanTypeError
+func (c *Cursor) Each(fn interface{}) error {
+	defer c.Close()
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		return &ScanTypeError{Type: reflect.TypeOf(fn)}
+	}
+	fnType := fnValue.Type()
+	if fnType.NumIn() != 1 || fnType.In(0).Kind() != reflect.Ptr || fnType.IsVariadic() ||
+		fnType.NumOut() > 1 || (fnType.NumOut() == 1 && fnType.Out(0) != errorType) {
+		return &ScanTypeError{Type: fnType}
+	}
+	rowType := fnType.In(0).Elem()
+	row := reflect.New(rowType)
+	zero := reflect.Zero(rowType)
+	ptr := row.Interface()
+	in := []reflect.Value{row}
+	for c.Next() {
+		row.Elem().Set(zero)
+		if err := c.Scan(ptr); err != nil {
+			return err
+		}
+		if out := fnValue.Call(in); len(out) == 1 {
+			if err, _ := out[0].Interface().(error); err != nil {
+				return err
+			}
+		}
+	}
+	return c.Err()
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
@@ -0,0 +1,87 @@
+package internel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestCursor(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery("query").WillReturnRows(
+		sqlmock.NewRows([]string{"a", "c"}).
+			AddRow("1", 1).
+			AddRow("2", 2),
+	)
+	rows, err := db.QueryContext(context.Background(), "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := GlobalScanner.Cursor(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row testRowsStruct
+	var list []testRowsStruct
+	for cursor.Next() {
+		if err := cursor.Scan(&row); err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, row)
+	}
+	if cursor.Err() != nil || len(list) != 2 || list[0].A != "1" || list[1].C != 2 {
+		t.Fatal(list, cursor.Err())
+	}
+	if err := cursor.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCursor_Each(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery("query").WillReturnRows(
+		sqlmock.NewRows([]string{"a", "c"}).
+			AddRow("1", 1).
+			AddRow("2", 2).
+			AddRow("3", 3),
+	)
+	rows, _ := db.QueryContext(context.Background(), "query")
+	cursor, _ := GlobalScanner.Cursor(rows)
+	stop := errors.New("stop")
+	var seen []int
+	err := cursor.Each(func(row *testRowsStruct) error {
+		seen = append(seen, row.C)
+		if row.C == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(seen) != 2 {
+		t.Fatal(seen, err)
+	}
+
+	invalid := []interface{}{
+		nil,
+		(func(row *testRowsStruct) error)(nil),
+		func(row testRowsStruct) {},
+		func(row *testRowsStruct) int { return 0 },
+		func(a, b *testRowsStruct) error { return nil },
+		1,
+	}
+	for _, fn := range invalid {
+		if err := cursor.Each(fn); !errors.Is(err, ErrInvalidScanType) {
+			t.Fatal(fn, err)
+		}
+	}
+
+	// 没有返回值的fn
+	mock.ExpectQuery("query").WillReturnRows(sqlmock.NewRows([]string{"c"}).AddRow(1).AddRow(2))
+	rows, _ = db.QueryContext(context.Background(), "query")
+	cursor, _ = GlobalScanner.Cursor(rows)
+	sum := 0
+	if err := cursor.Each(func(row *testRowsStruct) { sum += row.C }); err != nil || sum != 3 {
+		t.Fatal(sum, err)
+	}
+}
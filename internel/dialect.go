@@ -0,0 +1,206 @@
+package internel
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Dialect 数据库方言 描述不同数据库之间SQL语法的差异
+// SQLGenerator 根据方言生成标识符引号 参数占位符 分页等语句
+type Dialect interface {
+	// Name 方言名称 如 mysql postgres
+	Name() string
+	// Quote 为标识符加上引号 如 MySQL 的 `name` Postgres 的 "name"
+	Quote(ident string) string
+	// Placeholder 返回第n个参数的占位符 n从1开始
+	Placeholder(n int) string
+	// Returning 插入语句回传自增主键的方式
+	Returning() ReturningStyle
+	// LimitOffset 生成分页子句 limit小于0表示不限制条数 ordered表示语句中已经包含 ORDER BY
+	LimitOffset(limit, offset int64, ordered bool) string
+	// MaxPlaceholders 单条语句允许的最多参数个数
+	MaxPlaceholders() int
+	// FirstInsertID 根据多行插入后的LastInsertId计算第一行的自增主键
+	FirstInsertID(lastInsertID int64, rows int) int64
+	// OnConflict 生成插入冲突时更新update列的子句 conflict为判断冲突的列 方言不支持时返回ErrUnsupported
+	OnConflict(conflict, update []string) (string, error)
+}
+
+// ReturningStyle 插入语句回传自增主键的方式
+type ReturningStyle int
+
+const (
+	ReturningNone   ReturningStyle = iota // 不支持回传 通过 sql.Result 的 LastInsertId 获取
+	ReturningClause                       // INSERT ... VALUES (...) RETURNING col
+	ReturningOutput                       // INSERT ... OUTPUT INSERTED.col VALUES (...)
+)
+
+var (
+	// MySQL `ident` ?
+	MySQL Dialect = &dialect{
+		name:        "mysql",
+		quote:       [2]string{"`", "`"},
+		placeholder: questionPlaceholder,
+		returning:   ReturningNone,
+		limit:       limitOffset("18446744073709551615"),
+		maxArgs:     65535,
+		upsert:      onDuplicateKey,
+	}
+	// Postgres "ident" $1
+	Postgres Dialect = &dialect{
+		name:        "postgres",
+		quote:       [2]string{`"`, `"`},
+		placeholder: prefixPlaceholder("$"),
+		returning:   ReturningClause,
+		limit:       limitOffset(""),
+		maxArgs:     65535,
+		upsert:      onConflict,
+	}
+	// SQLite "ident" ?
+	SQLite Dialect = &dialect{
+		name:          "sqlite",
+		quote:         [2]string{`"`, `"`},
+		placeholder:   questionPlaceholder,
+		returning:     ReturningNone,
+		limit:         limitOffset("-1"),
+		maxArgs:       32766,
+		lastIDOfBatch: true,
+		upsert:        onConflict,
+	}
+	// SQLServer [ident] @p1
+	SQLServer Dialect = &dialect{
+		name:        "sqlserver",
+		quote:       [2]string{"[", "]"},
+		placeholder: prefixPlaceholder("@p"),
+		returning:   ReturningOutput,
+		limit:       offsetFetch,
+		maxArgs:     2100,
+	}
+)
+
+type dialect struct {
+	name        string
+	quote       [2]string
+	placeholder func(n int) string
+	returning   ReturningStyle
+	limit       func(limit, offset int64, ordered bool) string
+	maxArgs     int
+	// 多行插入后LastInsertId返回的是最后一行的主键 如SQLite 否则为第一行 如MySQL
+	lastIDOfBatch bool
+	upsert        func(d Dialect, conflict, update []string) (string, error)
+}
+
+func (d *dialect) Name() string {
+	return d.name
+}
+
+// Quote 标识符中出现的右引号会被转义为两个
+func (d *dialect) Quote(ident string) string {
+	return d.quote[0] + strings.Replace(ident, d.quote[1], d.quote[1]+d.quote[1], -1) + d.quote[1]
+}
+
+func (d *dialect) Placeholder(n int) string {
+	return d.placeholder(n)
+}
+
+func (d *dialect) Returning() ReturningStyle {
+	return d.returning
+}
+
+func (d *dialect) LimitOffset(limit, offset int64, ordered bool) string {
+	return d.limit(limit, offset, ordered)
+}
+
+func (d *dialect) MaxPlaceholders() int {
+	return d.maxArgs
+}
+
+func (d *dialect) FirstInsertID(lastInsertID int64, rows int) int64 {
+	if d.lastIDOfBatch {
+		return lastInsertID - int64(rows) + 1
+	}
+	return lastInsertID
+}
+
+func (d *dialect) OnConflict(conflict, update []string) (string, error) {
+	if d.upsert == nil {
+		return "", ErrUnsupported
+	}
+	return d.upsert(d, conflict, update)
+}
+
+func questionPlaceholder(int) string {
+	return "?"
+}
+
+func prefixPlaceholder(prefix string) func(n int) string {
+	return func(n int) string {
+		return prefix + strconv.Itoa(n)
+	}
+}
+
+// limitOffset 生成 LIMIT n OFFSET m 形式的分页
+// noLimit 为只有offset时LIMIT需要填写的值 为空表示可以省略LIMIT
+func limitOffset(noLimit string) func(limit, offset int64, ordered bool) string {
+	return func(limit, offset int64, ordered bool) string {
+		var parts []string
+		if limit >= 0 {
+			parts = append(parts, "LIMIT "+strconv.FormatInt(limit, 10))
+		} else if offset > 0 && noLimit != "" {
+			parts = append(parts, "LIMIT "+noLimit)
+		}
+		if offset > 0 {
+			parts = append(parts, "OFFSET "+strconv.FormatInt(offset, 10))
+		}
+		return strings.Join(parts, " ")
+	}
+}
+
+// offsetFetch SQL Server 的分页 OFFSET FETCH 必须跟在 ORDER BY 之后
+func offsetFetch(limit, offset int64, ordered bool) string {
+	if limit < 0 && offset <= 0 {
+		return ""
+	}
+	sql := ""
+	if !ordered {
+		sql = "ORDER BY (SELECT NULL) "
+	}
+	sql += "OFFSET " + strconv.FormatInt(offset, 10) + " ROWS"
+	if limit >= 0 {
+		sql += " FETCH NEXT " + strconv.FormatInt(limit, 10) + " ROWS ONLY"
+	}
+	return sql
+}
+
+// onDuplicateKey MySQL 的 ON DUPLICATE KEY UPDATE 冲突的判断由表的唯一索引决定 忽略conflict
+func onDuplicateKey(d Dialect, conflict, update []string) (string, error) {
+	if len(update) == 0 {
+		return "", errors.New("upsert requires at least one column to update")
+	}
+	sets := make([]string, 0, len(update))
+	for _, col := range update {
+		sets = append(sets, d.Quote(col)+"=VALUES("+d.Quote(col)+")")
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ","), nil
+}
+
+// onConflict Postgres SQLite 的 ON CONFLICT (...) DO UPDATE 没有需要更新的列时 DO NOTHING
+func onConflict(d Dialect, conflict, update []string) (string, error) {
+	if len(conflict) == 0 {
+		return "", errors.New("upsert requires conflict columns")
+	}
+	target := make([]string, 0, len(conflict))
+	for _, col := range conflict {
+		target = append(target, d.Quote(col))
+	}
+	sql := "ON CONFLICT (" + strings.Join(target, ",") + ") DO "
+	if len(update) == 0 {
+		return sql + "NOTHING", nil
+	}
+	sets := make([]string, 0, len(update))
+	for _, col := range update {
+		sets = append(sets, d.Quote(col)+"=EXCLUDED."+d.Quote(col))
+	}
+	return sql + "UPDATE SET " + strings.Join(sets, ","), nil
+}
@@ -0,0 +1,154 @@
+package internel
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDialect_Quote(t *testing.T) {
+	if q := MySQL.Quote("a`b"); q != "`a``b`" {
+		t.Fatal(q)
+	}
+	if q := Postgres.Quote(`a"b`); q != `"a""b"` {
+		t.Fatal(q)
+	}
+	if q := SQLServer.Quote("a]b"); q != "[a]]b]" {
+		t.Fatal(q)
+	}
+}
+
+var limitOffsetTestTable = []struct {
+	dialect Dialect
+	limit   int64
+	offset  int64
+	ordered bool
+	sql     string
+}{
+	{MySQL, 10, 0, false, "LIMIT 10"},
+	{MySQL, 10, 20, false, "LIMIT 10 OFFSET 20"},
+	{MySQL, -1, 20, false, "LIMIT 18446744073709551615 OFFSET 20"},
+	{MySQL, -1, 0, false, ""},
+	{Postgres, -1, 20, false, "OFFSET 20"},
+	{SQLite, -1, 20, false, "LIMIT -1 OFFSET 20"},
+	{SQLServer, 10, 20, true, "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+	{SQLServer, 10, 0, false, "ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"},
+	{SQLServer, -1, 0, false, ""},
+}
+
+func TestDialect_LimitOffset(t *testing.T) {
+	for _, test := range limitOffsetTestTable {
+		sql := test.dialect.LimitOffset(test.limit, test.offset, test.ordered)
+		if sql != test.sql {
+			t.Fatal(test.dialect.Name(), test.limit, test.offset, sql)
+		}
+	}
+}
+
+func TestSQLGenerator_Dialect(t *testing.T) {
+	type ThisUseTypeName struct {
+		Id       int
+		Name     string
+		ShowName string
+	}
+	ts := &ThisUseTypeName{Id: 3, Name: "thisisname"}
+
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), Postgres)
+	sql, _, _ := sg.PrepareInsert(ts)
+	if sql != `INSERT INTO "this_use_type_name" ("id","name","show_name") VALUES ($1,$2,$3)` {
+		t.Fatal(sql)
+	}
+	sql, args, _ := sg.PrepareUpdateByID(ts)
+	if sql != `UPDATE "this_use_type_name" SET "name"=$1,"show_name"=$2 WHERE "id" = $3` {
+		t.Fatal(sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"thisisname", "", 3}) {
+		t.Fatal(args)
+	}
+
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), SQLServer)
+	sql, _ = sg.PrepareSelectFrom(ts)
+	if sql != "SELECT [id],[name],[show_name] FROM [this_use_type_name] " {
+		t.Fatal(sql)
+	}
+	sql, _, _ = sg.PrepareUpdateByID(ts)
+	if sql != "UPDATE [this_use_type_name] SET [name]=@p1,[show_name]=@p2 WHERE [id] = @p3" {
+		t.Fatal(sql)
+	}
+}
+
+func TestSQLGenerator_PrepareInsertObject(t *testing.T) {
+	type ThisUseTypeName struct {
+		Id   int64
+		Name string
+	}
+	ts := &ThisUseTypeName{Name: "thisisname"}
+
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), Postgres)
+	sql, _, key, _ := sg.PrepareInsertObject(ts)
+	if sql != `INSERT INTO "this_use_type_name" ("name") VALUES ($1) RETURNING "id"` || key != KeyReturning {
+		t.Fatal(sql, key)
+	}
+
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), SQLServer)
+	sql, _, key, _ = sg.PrepareInsertObject(ts)
+	if sql != "INSERT INTO [this_use_type_name] ([name]) OUTPUT INSERTED.[id] VALUES (@p1)" || key != KeyReturning {
+		t.Fatal(sql, key)
+	}
+
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	_, _, key, _ = sg.PrepareInsertObject(ts)
+	if key != KeyLastInsertID {
+		t.Fatal(key)
+	}
+	if err := sg.SetInsertID(ts, 7); err != nil || ts.Id != 7 {
+		t.Fatal(err, ts.Id)
+	}
+	sql, _, key, _ = sg.PrepareInsertObject(ts)
+	if sql != "INSERT INTO `this_use_type_name` (`id`,`name`) VALUES (?,?)" || key != KeyGiven {
+		t.Fatal(sql, key)
+	}
+}
+
+func TestSQLGenerator_PrepareUpsert(t *testing.T) {
+	type UpsertUser struct {
+		ID    int64
+		Name  string
+		Email string
+	}
+	u := &UpsertUser{Name: "a", Email: "a@b.c"}
+
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	sql, args, err := sg.PrepareUpsert(u, []string{"email"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "INSERT INTO `upsert_user` (`name`,`email`) VALUES (?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)" {
+		t.Fatal(sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a", "a@b.c"}) {
+		t.Fatal(args)
+	}
+
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), Postgres)
+	sql, _, _ = sg.PrepareUpsert(u, []string{"email"}, nil)
+	if sql != `INSERT INTO "upsert_user" ("name","email") VALUES ($1,$2) ON CONFLICT ("email") DO UPDATE SET "name"=EXCLUDED."name"` {
+		t.Fatal(sql)
+	}
+	u.ID = 3
+	sql, _, _ = sg.PrepareUpsert(u, nil, []string{"email"})
+	if sql != `INSERT INTO "upsert_user" ("id","name","email") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "email"=EXCLUDED."email"` {
+		t.Fatal(sql)
+	}
+	sql, _, _ = sg.PrepareUpsert(u, nil, []string{})
+	if sql != `INSERT INTO "upsert_user" ("id","name","email") VALUES ($1,$2,$3) ON CONFLICT ("id") DO NOTHING` {
+		t.Fatal(sql)
+	}
+	if _, _, err := sg.PrepareUpsert(u, nil, []string{"unknown"}); err == nil {
+		t.Fatal("unknown column must be error")
+	}
+
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), SQLServer)
+	if _, _, err := sg.PrepareUpsert(u, nil, nil); err != ErrUnsupported {
+		t.Fatal(err)
+	}
+}
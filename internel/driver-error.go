@@ -0,0 +1,197 @@
+package internel
+
+import (
+	"errors"
+	"reflect"
+)
+
+// driverErrorCode 从驱动返回的错误中取出的错误码 不依赖具体的驱动包
+// 按错误的具体类型(包路径与类型名)识别驱动 见driverErrorTypes 实现了SQLState()的错误按SQLSTATE识别
+type driverErrorCode struct {
+	mysql        int    // MySQL 错误号 没有时为0
+	sqlState     string // Postgres SQLSTATE 没有时为空
+	sqlite       int    // SQLite 主错误码 没有时为0
+	sqliteExtend int    // SQLite 扩展错误码 没有时为0
+	sqlServer    int    // SQL Server 错误号 没有时为0
+}
+
+// driverKind 错误所属的驱动
+type driverKind int
+
+const (
+	driverUnknown driverKind = iota
+	driverMySQL
+	driverPostgres
+	driverSQLite
+	driverSQLServer
+)
+
+// driverErrorTypes 已知驱动的错误类型 键为 包路径.类型名 其他类型的错误即使有同名属性也不会被识别
+var driverErrorTypes = map[string]driverKind{
+	"github.com/go-sql-driver/mysql.MySQLError": driverMySQL,     // Number uint16
+	"github.com/lib/pq.Error":                   driverPostgres,  // Code pq.ErrorCode
+	"github.com/jackc/pgconn.PgError":           driverPostgres,  // Code string
+	"github.com/jackc/pgx/v5/pgconn.PgError":    driverPostgres,  // Code string
+	"github.com/mattn/go-sqlite3.Error":         driverSQLite,    // Code ErrNo ExtendedCode ErrNoExtended
+	"modernc.org/sqlite.Error":                  driverSQLite,    // Code() int
+	"github.com/microsoft/go-mssqldb.Error":     driverSQLServer, // Number int32
+	"github.com/denisenkom/go-mssqldb.Error":    driverSQLServer, // Number int32
+}
+
+type sqlStater interface {
+	SQLState() string
+}
+
+type sqliteCoder interface {
+	Code() int
+}
+
+// errorCodes 沿着错误链取出所有可以识别的错误码
+func errorCodes(err error) (codes []driverErrorCode) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if code, ok := codeOf(err); ok {
+			codes = append(codes, code)
+		}
+	}
+	return
+}
+
+// driverOf 按错误的具体类型识别驱动 返回驱动与去掉指针后的值
+func driverOf(err error) (driverKind, reflect.Value) {
+	val := reflect.ValueOf(err)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return driverUnknown, val
+		}
+		val = val.Elem()
+	}
+	typ := val.Type()
+	return driverErrorTypes[typ.PkgPath()+"."+typ.Name()], val
+}
+
+func codeOf(err error) (code driverErrorCode, ok bool) {
+	kind, val := driverOf(err)
+	switch kind {
+	case driverMySQL:
+		if number := val.FieldByName("Number"); number.IsValid() && isUintKind(number.Kind()) {
+			code.mysql = int(number.Uint())
+			return code, code.mysql != 0
+		}
+	case driverPostgres:
+		if stater, is := err.(sqlStater); is {
+			code.sqlState = stater.SQLState()
+		} else if c := val.FieldByName("Code"); c.IsValid() && c.Kind() == reflect.String {
+			code.sqlState = c.String()
+		}
+		return code, code.sqlState != ""
+	case driverSQLite:
+		if coder, is := err.(sqliteCoder); is {
+			code.sqliteExtend = coder.Code()
+			code.sqlite = code.sqliteExtend & 0xff
+			return code, true
+		}
+		if c := val.FieldByName("Code"); c.IsValid() && isIntKind(c.Kind()) {
+			code.sqlite = int(c.Int())
+			if extended := val.FieldByName("ExtendedCode"); extended.IsValid() && isIntKind(extended.Kind()) {
+				code.sqliteExtend = int(extended.Int())
+			}
+			return code, true
+		}
+	case driverSQLServer:
+		if number := val.FieldByName("Number"); number.IsValid() && isIntKind(number.Kind()) {
+			code.sqlServer = int(number.Int())
+			return code, code.sqlServer != 0
+		}
+	default:
+		// 其他实现了SQLState()的错误 如包装后的Postgres错误
+		if stater, is := err.(sqlStater); is {
+			code.sqlState = stater.SQLState()
+			return code, code.sqlState != ""
+		}
+	}
+	return
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uint64
+}
+
+// errorClass 一类错误在各数据库中对应的错误码
+type errorClass struct {
+	mysql        []int
+	sqlState     []string
+	sqlite       []int // 主错误码
+	sqliteExtend []int // 扩展错误码
+	sqlServer    []int
+}
+
+func (c *errorClass) match(err error) bool {
+	for _, code := range errorCodes(err) {
+		if code.mysql != 0 && containsInt(c.mysql, code.mysql) ||
+			code.sqlState != "" && containsString(c.sqlState, code.sqlState) ||
+			code.sqliteExtend != 0 && containsInt(c.sqliteExtend, code.sqliteExtend) ||
+			code.sqlite != 0 && containsInt(c.sqlite, code.sqlite) ||
+			code.sqlServer != 0 && containsInt(c.sqlServer, code.sqlServer) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	duplicateKeyClass = &errorClass{
+		mysql:        []int{1062, 1586}, // ER_DUP_ENTRY ER_DUP_ENTRY_WITH_KEY_NAME
+		sqlState:     []string{"23505"}, // unique_violation
+		sqliteExtend: []int{2067, 1555}, // SQLITE_CONSTRAINT_UNIQUE SQLITE_CONSTRAINT_PRIMARYKEY
+		sqlServer:    []int{2627, 2601}, // 违反唯一约束 唯一索引重复
+	}
+	deadlockClass = &errorClass{
+		mysql:     []int{1213},       // ER_LOCK_DEADLOCK
+		sqlState:  []string{"40P01"}, // deadlock_detected
+		sqlite:    []int{5, 6},       // SQLITE_BUSY SQLITE_LOCKED SQLite 不检测死锁 以锁冲突代替
+		sqlServer: []int{1205},       // 事务被选为死锁牺牲品
+	}
+	foreignKeyClass = &errorClass{
+		mysql:        []int{1216, 1217, 1451, 1452}, // ER_NO_REFERENCED_ROW ER_ROW_IS_REFERENCED 及其 _2
+		sqlState:     []string{"23503"},             // foreign_key_violation
+		sqliteExtend: []int{787},                    // SQLITE_CONSTRAINT_FOREIGNKEY
+		sqlServer:    []int{547},                    // 违反外键等约束
+	}
+)
+
+// IsDuplicateKey 错误是否为唯一键或主键冲突
+func IsDuplicateKey(err error) bool {
+	return duplicateKeyClass.match(err)
+}
+
+// IsDeadlock 错误是否为死锁 SQLite 中为 SQLITE_BUSY SQLITE_LOCKED 通常可以重试事务
+func IsDeadlock(err error) bool {
+	return deadlockClass.match(err)
+}
+
+// IsForeignKeyViolation 错误是否为违反外键约束
+func IsForeignKeyViolation(err error) bool {
+	return foreignKeyClass.match(err)
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
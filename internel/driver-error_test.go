@@ -0,0 +1,117 @@
+package internel
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// 测试用的错误类型冒充对应的驱动
+func init() {
+	register := func(err interface{}, kind driverKind) {
+		typ := reflect.TypeOf(err)
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		driverErrorTypes[typ.PkgPath()+"."+typ.Name()] = kind
+	}
+	register(&testMySQLError{}, driverMySQL)
+	register(&testPQError{}, driverPostgres)
+	register(testSQLiteError{}, driverSQLite)
+	register(&testMSSQLError{}, driverSQLServer)
+}
+
+// 与 github.com/go-sql-driver/mysql 的 MySQLError 结构相同
+type testMySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *testMySQLError) Error() string { return e.Message }
+
+// 与 github.com/lib/pq 的 Error 结构相同
+type testPQErrorCode string
+
+type testPQError struct {
+	Code    testPQErrorCode
+	Message string
+}
+
+func (e *testPQError) Error() string { return e.Message }
+
+// 与 github.com/mattn/go-sqlite3 的 Error 结构相同
+type testSQLiteError struct {
+	Code         int
+	ExtendedCode int
+}
+
+func (e testSQLiteError) Error() string { return "sqlite" }
+
+// 与 github.com/microsoft/go-mssqldb 的 Error 结构相同
+type testMSSQLError struct {
+	Number  int32
+	Message string
+}
+
+func (e testMSSQLError) Error() string { return e.Message }
+
+// 与驱动错误属性同名的其他错误
+type testCodeError struct {
+	Code         int
+	ExtendedCode int
+	Number       uint16
+}
+
+func (e *testCodeError) Error() string { return "code" }
+
+type testPgxError struct{ code string }
+
+func (e *testPgxError) Error() string    { return "pgx" }
+func (e *testPgxError) SQLState() string { return e.code }
+
+func TestErrorClassification(t *testing.T) {
+	table := []struct {
+		err                        error
+		duplicate, deadlock, fkErr bool
+	}{
+		{&testMySQLError{Number: 1062}, true, false, false},
+		{&testMySQLError{Number: 1213}, false, true, false},
+		{&testMySQLError{Number: 1452}, false, false, true},
+		{&testPQError{Code: "23505"}, true, false, false},
+		{&testPQError{Code: "40P01"}, false, true, false},
+		{&testPgxError{code: "23503"}, false, false, true},
+		{testSQLiteError{Code: 19, ExtendedCode: 2067}, true, false, false},
+		{testSQLiteError{Code: 19, ExtendedCode: 787}, false, false, true},
+		{testSQLiteError{Code: 5, ExtendedCode: 5}, false, true, false},
+		{testMSSQLError{Number: 2627}, true, false, false},
+		{&testMSSQLError{Number: 1205}, false, true, false},
+		{testMSSQLError{Number: 547}, false, false, true},
+		{testMSSQLError{Number: 1213}, false, false, false},
+		{&testMySQLError{Number: 1205}, false, false, false},
+		{&testPQError{Code: "1213"}, false, false, false},
+		{fmt.Errorf("insert user: %w", &testMySQLError{Number: 1062}), true, false, false},
+		{&testCodeError{Code: 5, ExtendedCode: 5}, false, false, false},
+		{&testCodeError{Number: 1213}, false, false, false},
+		{&testCodeError{Code: 19, ExtendedCode: 2067}, false, false, false},
+		{fmt.Errorf("retry: %w", &testCodeError{Number: 1062}), false, false, false},
+		{errors.New("1062"), false, false, false},
+		{nil, false, false, false},
+	}
+	for i, test := range table {
+		if IsDuplicateKey(test.err) != test.duplicate || IsDeadlock(test.err) != test.deadlock || IsForeignKeyViolation(test.err) != test.fkErr {
+			t.Fatal(i, test.err)
+		}
+	}
+}
+
+func TestScanTypeError(t *testing.T) {
+	_, err := NewTypeInfoFactory().Get(map[int]int{})
+	var typeErr *ScanTypeError
+	if !errors.Is(err, ErrInvalidScanType) || !errors.As(err, &typeErr) || typeErr.Type.String() != "map[int]int" {
+		t.Fatal(err)
+	}
+	if _, err = NewTypeInfoFactory().Get(nil); !errors.Is(err, ErrInvalidScanType) {
+		t.Fatal(err)
+	}
+}
@@ -1,7 +1,29 @@
 package internel
 
-import "errors"
+import (
+	"errors"
+	"reflect"
+)
 
 var (
 	ErrInvalidScanType = errors.New("invalid scan type")
+	ErrNotAddressable  = errors.New("object is not addressable, pass a pointer to struct")
+	ErrUnsupported     = errors.New("operation is not supported by the dialect")
+	ErrNoPrimaryKey    = errors.New("no primary key declared, tag a field with `db:\",pk\"` or name it id")
 )
+
+// ScanTypeError 不支持的扫描目标或对象类型 errors.Is(err, ErrInvalidScanType) 为true
+type ScanTypeError struct {
+	Type reflect.Type
+}
+
+func (e *ScanTypeError) Error() string {
+	if e.Type == nil {
+		return ErrInvalidScanType.Error() + ": nil"
+	}
+	return ErrInvalidScanType.Error() + ": " + e.Type.String()
+}
+
+func (e *ScanTypeError) Is(target error) bool {
+	return target == ErrInvalidScanType
+}
@@ -0,0 +1,63 @@
+package internel
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// KeyedBy 扫描到 map[K]T map[K]*T map[K][]T 时指定作为键的列
+type KeyedBy struct {
+	Column string
+	Ptr    interface{}
+}
+
+// keyColumn 作为map键的列 扫描时同时写入属性与键
+type keyColumn struct {
+	Column
+	key reflect.Value // 指向键的指针
+}
+
+func (c *keyColumn) PointerOf(v reflect.Value) (val reflect.Value, err error) {
+	dest, err := c.Column.PointerOf(v)
+	if err != nil {
+		return
+	}
+	return reflect.ValueOf(&keyValue{dest: dest.Interface(), key: c.key.Interface()}), nil
+}
+
+type keyValue struct {
+	dest interface{}
+	key  interface{}
+}
+
+func (k *keyValue) Scan(src interface{}) error {
+	if err := convertAssign(k.key, src); err != nil {
+		return fmt.Errorf("scan map key: %w", err)
+	}
+	return convertAssign(k.dest, src)
+}
+
+// keyIndex 选择作为键的列 依次为指定的列 唯一的主键列(pk标签或名为id) 第一列
+func (builder *ValuesProducerBuilder) keyIndex(info *TypeInfo, columnNames []string, column string) (int, error) {
+	if column != "" {
+		for i, name := range columnNames {
+			if name == column {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("key column %q not found in result columns: %s", column, strings.Join(columnNames, ","))
+	}
+	if len(columnNames) == 0 {
+		return 0, ErrInvalidScanType
+	}
+	keys := primaryKeys(toNamedFields(builder.fieldProducer.Fields(info.ElemType)))
+	if len(keys) == 1 {
+		for i, name := range columnNames {
+			if name == keys[0].Name {
+				return i, nil
+			}
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,106 @@
+package internel
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+)
+
+// columnTyper 可以获取列类型的结果集 如 *sql.Rows
+type columnTyper interface {
+	ColumnTypes() ([]*sql.ColumnType, error)
+}
+
+// columnTypes 获取结果集的列类型 不支持或出错时返回nil
+func columnTypes(rows SQLRows) []*sql.ColumnType {
+	if typer, ok := rows.(columnTyper); ok {
+		if types, err := typer.ColumnTypes(); err == nil {
+			return types
+		}
+	}
+	return nil
+}
+
+// MapColumn 以列名为键将值写入 map[string]interface{}
+// 驱动提供了列类型时按ScanType转换 如 MySQL 文本协议返回的 []byte 转换为 int64
+// 否则 []byte 转换为string 二进制类型的列(BLOB BINARY BYTEA)保持 []byte
+type MapColumn struct {
+	Name     string
+	scanType reflect.Type
+	binary   bool
+}
+
+// NewMapColumn 创建写入map的列 ct可以为nil
+func NewMapColumn(name string, ct *sql.ColumnType) *MapColumn {
+	c := &MapColumn{Name: name}
+	if ct == nil {
+		return c
+	}
+	typeName := strings.ToUpper(ct.DatabaseTypeName())
+	c.binary = strings.Contains(typeName, "BLOB") || strings.Contains(typeName, "BINARY") || typeName == "BYTEA"
+	if scanType := ct.ScanType(); scanType != nil && sensibleScanType(scanType) {
+		c.scanType = scanType
+	}
+	return c
+}
+
+// sensibleScanType 可以直接作为map值的类型 排除 []byte sql.RawBytes interface{} 等
+func sensibleScanType(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	case reflect.Struct:
+		return typ == timeType || (typ.Implements(valuerType) && reflect.PtrTo(typ).Implements(sqlScannerType))
+	}
+	return false
+}
+
+// PointerOf v为当前行的map
+func (c *MapColumn) PointerOf(v reflect.Value) (val reflect.Value, err error) {
+	return reflect.ValueOf(&mapValue{row: v, column: c}), nil
+}
+
+// convert 将驱动返回的值转换为map中保存的值
+func (c *MapColumn) convert(src interface{}) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+	if c.scanType != nil {
+		dest := reflect.New(c.scanType)
+		// 转换失败时按默认规则处理 如未开启parseTime时 DATETIME 为 []byte
+		if err := convertAssign(dest.Interface(), src); err == nil {
+			value := dest.Elem().Interface()
+			if valuer, ok := value.(driver.Valuer); ok {
+				return valuer.Value()
+			}
+			return value, nil
+		}
+	}
+	if b, ok := src.([]byte); ok {
+		if c.binary {
+			// 驱动可能复用缓冲区 需要复制
+			return append([]byte(nil), b...), nil
+		}
+		return string(b), nil
+	}
+	return src, nil
+}
+
+// mapValue 扫描一列并写入map
+type mapValue struct {
+	row    reflect.Value
+	column *MapColumn
+}
+
+func (m *mapValue) Scan(src interface{}) error {
+	value, err := m.column.convert(src)
+	if err != nil {
+		return err
+	}
+	// 使用interface类型的Value 值为nil时同样写入键
+	m.row.SetMapIndex(reflect.ValueOf(m.column.Name), reflect.ValueOf(&value).Elem())
+	return nil
+}
@@ -2,15 +2,56 @@ package internel
 
 import (
 	"reflect"
+	"strings"
 )
 
 type Mapper func(name string, tag reflect.StructTag) string
 
+// TagMapper 使用db标签中逗号之前的部分作为列名 逗号之后为列的选项 如 `db:"user_id,pk"`
 func TagMapper(name string, tag reflect.StructTag) string {
 	dbName, _ := tag.Lookup("db")
+	if pos := strings.IndexByte(dbName, ','); pos != -1 {
+		dbName = dbName[:pos]
+	}
 	return dbName
 }
 
+// fieldOption 通过db标签逗号之后的选项声明的列属性
+type fieldOption uint
+
+const (
+	optPrimaryKey    fieldOption = 1 << iota // pk 主键 多个属性均声明时为联合主键
+	optAutoIncrement                         // autoincr 自增列 插入时为零值则由数据库生成
+	optReadOnly                              // readonly 只用于查询 不参与插入与更新 如计算列 数据库维护的updated_at
+	optInsertOnly                            // insertonly 只在插入时写入 不参与更新 如created_at
+	optOmitEmpty                             // omitempty 为零值时不参与插入与更新
+	optDefault                               // default 列有数据库默认值 为零值时不参与插入
+	optVersion                               // version 乐观锁版本列 必须为整数 更新时加一 按主键更新时作为条件
+)
+
+var fieldOptionNames = map[string]fieldOption{
+	"pk":         optPrimaryKey,
+	"autoincr":   optAutoIncrement,
+	"readonly":   optReadOnly,
+	"insertonly": optInsertOnly,
+	"omitempty":  optOmitEmpty,
+	"default":    optDefault,
+	"version":    optVersion,
+}
+
+// ignoredFieldName 列名为 - 的属性被忽略 如 `db:"-"`
+const ignoredFieldName = "-"
+
+// parseFieldOptions 解析db标签中的选项 不认识的选项被忽略
+func parseFieldOptions(tag reflect.StructTag) (options fieldOption) {
+	dbTag, _ := tag.Lookup("db")
+	parts := strings.Split(dbTag, ",")
+	for _, part := range parts[1:] {
+		options |= fieldOptionNames[strings.TrimSpace(part)]
+	}
+	return
+}
+
 const x = 'A' - 'a'
 
 func SnakeMapper(name string, tag reflect.StructTag) string {
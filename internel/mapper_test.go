@@ -1,6 +1,7 @@
 package internel
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -24,3 +25,24 @@ func TestToSnake(t *testing.T) {
 		}
 	}
 }
+
+func TestTagMapper(t *testing.T) {
+	type testStruct struct {
+		UserID int64  `db:"user_id,pk,autoincr"`
+		Name   string `db:",pk"`
+		Email  string `db:"mail"`
+	}
+	typ := reflect.TypeOf(testStruct{})
+	if name := TagMapper("UserID", typ.Field(0).Tag); name != "user_id" {
+		t.Fatal(name)
+	}
+	if name := SnakeMapper("Name", typ.Field(1).Tag); name != "name" {
+		t.Fatal(name)
+	}
+	if options := parseFieldOptions(typ.Field(0).Tag); options != optPrimaryKey|optAutoIncrement {
+		t.Fatal(options)
+	}
+	if options := parseFieldOptions(typ.Field(2).Tag); options != 0 {
+		t.Fatal(options)
+	}
+}
@@ -0,0 +1,174 @@
+package internel
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// NamedStatement 编译后的命名参数语句
+type NamedStatement struct {
+	SQL   string   // 命名参数替换为方言占位符后的语句
+	Names []string // 每个占位符对应的参数名 按出现顺序排列 同名参数出现多次时重复
+}
+
+// CompileNamed 将sql中的 :name 形式的参数替换为方言的占位符
+// 字符串 引号标识符与注释中的内容不会被替换 不允许与 ? $1 等位置参数混用
+func CompileNamed(d Dialect, sql string) (*NamedStatement, error) {
+	p := parseSQL(sql, true)
+	stmt := &NamedStatement{Names: make([]string, 0, len(p.tokens))}
+	buf := bytes.NewBuffer(make([]byte, 0, len(sql)))
+	for i, token := range p.tokens {
+		if token.kind != tokenNamed {
+			return nil, errors.New("named statement can not contain positional placeholders")
+		}
+		buf.WriteString(p.parts[i])
+		stmt.Names = append(stmt.Names, token.name)
+		buf.WriteString(d.Placeholder(len(stmt.Names)))
+	}
+	buf.WriteString(p.parts[len(p.parts)-1])
+	stmt.SQL = buf.String()
+	return stmt, nil
+}
+
+// PrepareNamed 按生成器的方言编译sql中的命名参数 并从arg中取出对应的参数
+// arg为结构体或指向结构体的指针时 参数名为属性映射后的列名 与扫描结果时相同
+// arg为键类型为string的map时 参数名为map的键
+// 参数名中的 . 用于访问嵌套的结构体或map 如 :user.name
+// 编译结果按sql缓存 每个生成器最多缓存namedCacheSize条
+func (s *SQLGenerator) PrepareNamed(sql string, arg interface{}) (string, []interface{}, error) {
+	stmt, err := s.compileNamed(sql)
+	if err != nil {
+		return "", nil, err
+	}
+	args := make([]interface{}, 0, len(stmt.Names))
+	val := reflect.ValueOf(arg)
+	for _, name := range stmt.Names {
+		v, err := s.namedValue(val, name)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, v.Interface())
+	}
+	return stmt.SQL, args, nil
+}
+
+// compileNamed 尝试获取缓存的编译结果
+func (s *SQLGenerator) compileNamed(sql string) (*NamedStatement, error) {
+	if stmt, ok := s.named.get(sql); ok {
+		return stmt, nil
+	}
+	stmt, err := CompileNamed(s.dialect, sql)
+	if err != nil {
+		return nil, err
+	}
+	s.named.put(sql, stmt)
+	return stmt, nil
+}
+
+// namedCacheSize 每个生成器最多缓存的命名参数语句数
+const namedCacheSize = 256
+
+// namedCache 命名参数语句的编译缓存 超出容量时淘汰最久未使用的语句
+type namedCache struct {
+	capacity int
+	locker   sync.Mutex
+	lru      *list.List // 最近使用的在前
+	items    map[string]*list.Element
+}
+
+type namedEntry struct {
+	sql  string
+	stmt *NamedStatement
+}
+
+func newNamedCache(capacity int) *namedCache {
+	return &namedCache{
+		capacity: capacity,
+		lru:      list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+func (c *namedCache) get(sql string) (*NamedStatement, bool) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	elem, ok := c.items[sql]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*namedEntry).stmt, true
+}
+
+func (c *namedCache) put(sql string, stmt *NamedStatement) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if elem, ok := c.items[sql]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.items[sql] = c.lru.PushFront(&namedEntry{sql: sql, stmt: stmt})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*namedEntry).sql)
+	}
+}
+
+// len 当前缓存的语句数
+func (c *namedCache) len() int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.lru.Len()
+}
+
+// namedValue 从结构体或map中取出名为name的参数 优先按完整名称查找 找不到时按 . 逐级查找
+func (s *SQLGenerator) namedValue(val reflect.Value, name string) (reflect.Value, error) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}, fmt.Errorf("named parameter %q: nil value", name)
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return reflect.Value{}, fmt.Errorf("named parameter %q: nil value", name)
+	}
+	if v, ok, err := s.lookupNamed(val, name); ok || err != nil {
+		return v, err
+	}
+	if dot := strings.IndexByte(name, '.'); dot > 0 {
+		v, ok, err := s.lookupNamed(val, name[:dot])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if ok {
+			return s.namedValue(v, name[dot+1:])
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("named parameter %q not found", name)
+}
+
+// lookupNamed 在结构体或map中查找名为name的参数 ok表示是否找到
+func (s *SQLGenerator) lookupNamed(val reflect.Value, name string) (v reflect.Value, ok bool, err error) {
+	switch val.Kind() {
+	case reflect.Struct:
+		field, exist := s.fieldProducer.Fields(val.Type())[name]
+		if !exist {
+			return
+		}
+		v, err = field.ValueOf(val)
+		return v, err == nil, err
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return v, false, &ScanTypeError{Type: val.Type()}
+		}
+		v = val.MapIndex(reflect.ValueOf(name).Convert(val.Type().Key()))
+		return v, v.IsValid(), nil
+	}
+	return v, false, &ScanTypeError{Type: val.Type()}
+}
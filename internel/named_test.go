@@ -0,0 +1,82 @@
+package internel
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompileNamed(t *testing.T) {
+	stmt, err := CompileNamed(Postgres, "SELECT * FROM t WHERE a::int = :name AND b = ':x' AND c = :name OR d = :user.age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stmt.SQL != "SELECT * FROM t WHERE a::int = $1 AND b = ':x' AND c = $2 OR d = $3" {
+		t.Fatal(stmt.SQL)
+	}
+	if len(stmt.Names) != 3 || stmt.Names[0] != "name" || stmt.Names[1] != "name" || stmt.Names[2] != "user.age" {
+		t.Fatal(stmt.Names)
+	}
+	if _, err := CompileNamed(MySQL, "SELECT * FROM t WHERE a = :a AND b = ?"); err == nil {
+		t.Fatal("mixed placeholders should fail")
+	}
+}
+
+type namedUser struct {
+	ID   int64
+	Name string
+	Age  *int
+}
+
+func TestSQLGenerator_PrepareNamed(t *testing.T) {
+	gen := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	age := 18
+	sql, args, err := gen.PrepareNamed("SELECT * FROM t WHERE name = :name AND age > :age", &namedUser{Name: "a", Age: &age})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "SELECT * FROM t WHERE name = ? AND age > ?" || len(args) != 2 || args[0] != "a" || args[1] != &age {
+		t.Fatal(sql, args)
+	}
+
+	_, args, err = gen.PrepareNamed("SELECT * FROM t WHERE name = :user.name AND id = :id", map[string]interface{}{
+		"id":   1,
+		"user": namedUser{Name: "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != "b" || args[1] != 1 {
+		t.Fatal(args)
+	}
+
+	if _, _, err = gen.PrepareNamed("SELECT * FROM t WHERE x = :x", namedUser{}); err == nil {
+		t.Fatal("missing parameter should fail")
+	}
+}
+
+func TestNamedCache(t *testing.T) {
+	cache := newNamedCache(2)
+	a, b, c := &NamedStatement{SQL: "a"}, &NamedStatement{SQL: "b"}, &NamedStatement{SQL: "c"}
+	cache.put("a", a)
+	cache.put("b", b)
+	if stmt, ok := cache.get("a"); !ok || stmt != a {
+		t.Fatal(stmt)
+	}
+	cache.put("c", c)
+	if _, ok := cache.get("b"); ok {
+		t.Fatal("least recently used statement should be evicted")
+	}
+	if stmt, ok := cache.get("a"); !ok || stmt != a || cache.len() != 2 {
+		t.Fatal(stmt, cache.len())
+	}
+
+	gen := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	for i := 0; i < namedCacheSize+10; i++ {
+		if _, _, err := gen.PrepareNamed(fmt.Sprintf("SELECT * FROM t WHERE id = :id AND %d = %d", i, i), map[string]interface{}{"id": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gen.named.len() != namedCacheSize {
+		t.Fatal(gen.named.len())
+	}
+}
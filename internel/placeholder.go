@@ -0,0 +1,246 @@
+package internel
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tokenKind SQL中参数占位符的种类
+type tokenKind int
+
+const (
+	tokenQuestion tokenKind = iota // ? 按出现顺序对应参数
+	tokenOrdinal                   // $1 @p1 按编号对应参数
+	tokenNamed                     // :name 按名称对应参数
+)
+
+// sqlToken SQL中的一个参数占位符
+type sqlToken struct {
+	kind tokenKind
+	n    int    // tokenOrdinal 的编号 从1开始
+	name string // tokenNamed 的名称
+}
+
+// parsedSQL 拆分后的SQL parts[i] 为第i个占位符之前的文本 最后一段为最后一个占位符之后的文本
+// 因此 len(parts) == len(tokens)+1
+type parsedSQL struct {
+	parts  []string
+	tokens []sqlToken
+}
+
+// parseSQL 拆分出sql中的参数占位符 跳过字符串 引号标识符与注释中的内容
+// named为true时识别 :name 形式的命名参数 :: 类型转换不会被识别为参数
+func parseSQL(sql string, named bool) *parsedSQL {
+	p := &parsedSQL{}
+	last := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(sql, i, c)
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+		case c == '?':
+			p.parts = append(p.parts, sql[last:i])
+			p.tokens = append(p.tokens, sqlToken{kind: tokenQuestion})
+			last = i + 1
+		case c == '$' && !isIdentByte(prevByte(sql, i)):
+			if end, n := scanNumber(sql, i+1); n > 0 {
+				p.parts = append(p.parts, sql[last:i])
+				p.tokens = append(p.tokens, sqlToken{kind: tokenOrdinal, n: n})
+				last = end
+				i = end - 1
+			}
+		case c == '@' && i+1 < len(sql) && (sql[i+1] == 'p' || sql[i+1] == 'P') && !isIdentByte(prevByte(sql, i)):
+			if end, n := scanNumber(sql, i+2); n > 0 {
+				p.parts = append(p.parts, sql[last:i])
+				p.tokens = append(p.tokens, sqlToken{kind: tokenOrdinal, n: n})
+				last = end
+				i = end - 1
+			}
+		case named && c == ':' && prevByte(sql, i) != ':' && i+1 < len(sql) && isIdentStart(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && (isIdentByte(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			p.parts = append(p.parts, sql[last:i])
+			p.tokens = append(p.tokens, sqlToken{kind: tokenNamed, name: sql[i+1 : end]})
+			last = end
+			i = end - 1
+		}
+	}
+	p.parts = append(p.parts, sql[last:])
+	return p
+}
+
+// skipQuoted 跳过从start开始的引号内容 返回结束引号的位置 连续两个引号视为转义
+// 单引号字符串中反斜杠同样视为转义
+func skipQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if quote == '\'' {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(sql)
+}
+
+// scanNumber 读取从start开始的十进制数字 返回数字结束的位置 没有数字时n为0
+func scanNumber(sql string, start int) (end int, n int) {
+	end = start
+	for end < len(sql) && sql[end] >= '0' && sql[end] <= '9' {
+		end++
+	}
+	if end == start {
+		return start, 0
+	}
+	n, _ = strconv.Atoi(sql[start:end])
+	return
+}
+
+func prevByte(sql string, i int) byte {
+	if i == 0 {
+		return 0
+	}
+	return sql[i-1]
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentByte(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
+
+// Rebind 将使用 ? 作为占位符的sql转换为方言的占位符 编号从start开始
+// 字符串 引号标识符与注释中的 ? 不会被转换
+func Rebind(d Dialect, sql string, start int) string {
+	if d.Placeholder(start) == "?" {
+		return sql
+	}
+	p := parseSQL(sql, false)
+	if len(p.tokens) == 0 {
+		return sql
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(sql)+len(p.tokens)*2))
+	n := start
+	for i, token := range p.tokens {
+		buf.WriteString(p.parts[i])
+		if token.kind == tokenQuestion {
+			buf.WriteString(d.Placeholder(n))
+			n++
+			continue
+		}
+		// 已经是编号占位符时保持原编号
+		buf.WriteString(d.Placeholder(token.n))
+	}
+	buf.WriteString(p.parts[len(p.parts)-1])
+	return buf.String()
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// isExpandable 参数是否为需要展开的slice 排除[]byte与实现了driver.Valuer的类型
+func isExpandable(arg interface{}) (reflect.Value, bool) {
+	if arg == nil {
+		return reflect.Value{}, false
+	}
+	val := reflect.ValueOf(arg)
+	typ := val.Type()
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+		return val, false
+	}
+	if typ.Elem().Kind() == reflect.Uint8 || typ.Implements(valuerType) {
+		return val, false
+	}
+	return val, true
+}
+
+// ExpandSliceArgs 将slice参数展开为多个占位符 如 id IN (?) 与 []int{1,2,3} 展开为 id IN (?,?,?)
+// 只展开下标不小于from的参数 展开后按方言的占位符重新编号 $n 形式的编号占位符同样支持
+// 字符串 引号标识符与注释中的占位符不受影响 空slice返回错误
+func ExpandSliceArgs(d Dialect, sql string, args []interface{}, from int) (string, []interface{}, error) {
+	// 每个参数展开后的个数 不需要展开的为-1
+	sizes := make([]int, len(args))
+	expand := false
+	for i, arg := range args {
+		sizes[i] = -1
+		if i < from {
+			continue
+		}
+		if val, ok := isExpandable(arg); ok {
+			if val.Len() == 0 {
+				return "", nil, fmt.Errorf("empty slice passed as argument %d", i+1)
+			}
+			sizes[i] = val.Len()
+			expand = true
+		}
+	}
+	if !expand {
+		return sql, args, nil
+	}
+
+	// 每个原参数展开后的起始编号
+	starts := make([]int, len(args))
+	newArgs := make([]interface{}, 0, len(args)*2)
+	for i, arg := range args {
+		starts[i] = len(newArgs) + 1
+		if sizes[i] < 0 {
+			newArgs = append(newArgs, arg)
+			continue
+		}
+		val := reflect.ValueOf(arg)
+		for j := 0; j < sizes[i]; j++ {
+			newArgs = append(newArgs, val.Index(j).Interface())
+		}
+	}
+
+	p := parseSQL(sql, false)
+	buf := bytes.NewBuffer(make([]byte, 0, len(sql)+len(newArgs)*3))
+	question := 0
+	for i, token := range p.tokens {
+		buf.WriteString(p.parts[i])
+		index := token.n - 1
+		if token.kind == tokenQuestion {
+			index = question
+			question++
+		}
+		if index < 0 || index >= len(args) {
+			return "", nil, fmt.Errorf("placeholder %d has no matching argument", index+1)
+		}
+		size := sizes[index]
+		if size < 0 {
+			size = 1
+		}
+		for j := 0; j < size; j++ {
+			if j != 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(d.Placeholder(starts[index] + j))
+		}
+	}
+	buf.WriteString(p.parts[len(p.parts)-1])
+	return buf.String(), newArgs, nil
+}
@@ -0,0 +1,71 @@
+package internel
+
+import "testing"
+
+var rebindTestTable = []struct {
+	sql    string
+	result string
+}{
+	{"SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = $1 AND b = $2"},
+	{"SELECT '?', \"?\", `?` FROM t WHERE a = ?", "SELECT '?', \"?\", `?` FROM t WHERE a = $1"},
+	{"SELECT 'it''s ?' FROM t WHERE a = ?", "SELECT 'it''s ?' FROM t WHERE a = $1"},
+	{"SELECT 'a\\'?' FROM t WHERE a = ?", "SELECT 'a\\'?' FROM t WHERE a = $1"},
+	{"SELECT a -- ?\nFROM t WHERE a = ?", "SELECT a -- ?\nFROM t WHERE a = $1"},
+	{"SELECT a /* ? */ FROM t WHERE a = ?", "SELECT a /* ? */ FROM t WHERE a = $1"},
+	{"SELECT a FROM t WHERE a = $2 AND b = ?", "SELECT a FROM t WHERE a = $2 AND b = $1"},
+	{"SELECT a FROM t", "SELECT a FROM t"},
+}
+
+func TestRebind(t *testing.T) {
+	for _, test := range rebindTestTable {
+		if result := Rebind(Postgres, test.sql, 1); result != test.result {
+			t.Fatal(test.sql, "=>", result)
+		}
+	}
+	if result := Rebind(SQLServer, "a = ? AND b = ?", 3); result != "a = @p3 AND b = @p4" {
+		t.Fatal(result)
+	}
+	if result := Rebind(MySQL, "a = ?", 1); result != "a = ?" {
+		t.Fatal(result)
+	}
+}
+
+func TestParseSQL_Named(t *testing.T) {
+	p := parseSQL("SELECT a::int, ':x' FROM t WHERE a = :name AND b = :user.age", true)
+	if len(p.tokens) != 2 || p.tokens[0].name != "name" || p.tokens[1].name != "user.age" {
+		t.Fatal(p.tokens)
+	}
+	if p.parts[0] != "SELECT a::int, ':x' FROM t WHERE a = " || p.parts[2] != "" {
+		t.Fatal(p.parts)
+	}
+}
+
+func TestExpandSliceArgs(t *testing.T) {
+	sql, args, err := ExpandSliceArgs(MySQL, "SELECT * FROM t WHERE a = ? AND id IN (?) AND b = ?", []interface{}{1, []int{2, 3, 4}, []byte("x")}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "SELECT * FROM t WHERE a = ? AND id IN (?,?,?) AND b = ?" || len(args) != 5 || args[3] != 4 {
+		t.Fatal(sql, args)
+	}
+
+	sql, args, err = ExpandSliceArgs(Postgres, "UPDATE t SET a = $1 WHERE id IN ($2) AND b = $3", []interface{}{[]int{0}, []string{"x", "y"}, 5}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "UPDATE t SET a = $1 WHERE id IN ($2,$3) AND b = $4" || len(args) != 4 || args[3] != 5 {
+		t.Fatal(sql, args)
+	}
+
+	sql, _, err = ExpandSliceArgs(MySQL, "SELECT '?' FROM t WHERE a = ?", []interface{}{1}, 0)
+	if err != nil || sql != "SELECT '?' FROM t WHERE a = ?" {
+		t.Fatal(sql, err)
+	}
+
+	if _, _, err = ExpandSliceArgs(MySQL, "SELECT * FROM t WHERE id IN (?)", []interface{}{[]int{}}, 0); err == nil {
+		t.Fatal("empty slice should fail")
+	}
+	if _, _, err = ExpandSliceArgs(MySQL, "SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{[]int{1}}, 0); err == nil {
+		t.Fatal("missing argument should fail")
+	}
+}
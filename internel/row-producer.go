@@ -47,3 +47,61 @@ func rawTypeRP(v reflect.Value) RowProducer {
 		return v
 	}
 }
+
+func mapRP(v reflect.Value) RowProducer {
+	return func() reflect.Value {
+		if v.Elem().IsNil() {
+			v.Elem().Set(reflect.MakeMap(v.Type().Elem()))
+		}
+		return v.Elem()
+	}
+}
+
+func sliceOfMapRP(v reflect.Value) RowProducer {
+	slice := v.Elem()
+	mapType := slice.Type().Elem()
+	return func() reflect.Value {
+		created := reflect.MakeMap(mapType)
+		slice.Set(reflect.Append(slice, created))
+		return created
+	}
+}
+
+// keyedMapRP 按键写入map v为指向map的指针 key为指向键的指针 由键列在扫描时写入
+// 每一行在下一次调用或flush时才写入map 因为扫描之前无法得知键的值
+func keyedMapRP(typ SupportType, v reflect.Value, key reflect.Value) (p RowProducer, flush func()) {
+	m := v.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	rowType := m.Type().Elem()
+	if typ != TypeMapOfStruct {
+		rowType = rowType.Elem()
+	}
+	var pending reflect.Value
+	flush = func() {
+		if !pending.IsValid() {
+			return
+		}
+		k := key.Elem()
+		switch typ {
+		case TypeMapOfStruct:
+			m.SetMapIndex(k, pending.Elem())
+		case TypeMapOfPtrToStruct:
+			m.SetMapIndex(k, pending)
+		case TypeMapOfSliceOfStruct:
+			list := m.MapIndex(k)
+			if !list.IsValid() {
+				list = reflect.Zero(m.Type().Elem())
+			}
+			m.SetMapIndex(k, reflect.Append(list, pending.Elem()))
+		}
+		pending = reflect.Value{}
+	}
+	p = func() reflect.Value {
+		flush()
+		pending = reflect.New(rowType)
+		return pending
+	}
+	return
+}
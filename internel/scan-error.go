@@ -0,0 +1,47 @@
+package internel
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ColumnScanError 扫描某一行的某一列时出现的错误
+type ColumnScanError struct {
+	Row    int    // 出错的行 从0开始
+	Column string // 出错的列名 无法确定时为空
+	Field  string // 写入的目标 如结构体属性名 无法确定时为空
+	Err    error
+}
+
+func (e *ColumnScanError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("scan row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("scan row %d column %q into %s: %v", e.Row, e.Column, e.Field, e.Err)
+}
+
+func (e *ColumnScanError) Unwrap() error {
+	return e.Err
+}
+
+// wrapScanError 为rows.Scan返回的错误加上行号 列名与目标属性
+// 列的下标从 database/sql 的错误信息中获取 其他实现无法确定列时只包含行号
+func wrapScanError(err error, row int, columns []string, producer ValuesProducer) error {
+	index := -1
+	if _, scanErr := fmt.Sscanf(err.Error(), "sql: Scan error on column index %d", &index); scanErr != nil {
+		index = -1
+	}
+	e := &ColumnScanError{Row: row, Err: err}
+	if index < 0 || index >= len(columns) {
+		return e
+	}
+	e.Column = columns[index]
+	// database/sql 的错误信息中已经包含列的下标与名称 只保留原因
+	if inner := errors.Unwrap(err); inner != nil {
+		e.Err = inner
+	}
+	if described, ok := producer.(interface{ describe(i int) string }); ok {
+		e.Field = described.describe(index)
+	}
+	return e
+}
@@ -0,0 +1,71 @@
+package internel
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// ScanPlan 为固定的扫描目标类型预先计算的扫描信息 用于泛型方法 每个类型只需创建一次
+// 同时记住最近一次查询的列名与对应的列 列名相同时直接复用 不会随查询的不同而增长
+type ScanPlan struct {
+	builder    *ValuesProducerBuilder
+	generation uint32
+	typ        reflect.Type
+	info       *TypeInfo
+	last       atomic.Value // *planColumns
+}
+
+// planColumns 列名与对应的列 创建后不再修改
+type planColumns struct {
+	names   []string
+	columns []Column
+}
+
+// Planned 使用Plan扫描到Ptr 可以代替Ptr传给QueryContext SelectFrom GetByID等方法
+// Plan不是由当前扫描器创建或Ptr类型不符时忽略Plan
+type Planned struct {
+	Plan *ScanPlan
+	Ptr  interface{}
+}
+
+// Plan 为ptr的类型创建扫描计划 ptr支持的类型与Scan相同
+func (rs *RowsScanner) Plan(ptr interface{}) (*ScanPlan, error) {
+	info, err := rs.builder.typeInfoFactory.Get(ptr)
+	if err != nil {
+		return nil, err
+	}
+	return &ScanPlan{
+		builder:    rs.builder,
+		generation: atomic.LoadUint32(&rs.builder.generation),
+		typ:        reflect.TypeOf(ptr),
+		info:       info,
+	}, nil
+}
+
+// usableBy 计划是否可以用于builder扫描到obj
+func (p *ScanPlan) usableBy(builder *ValuesProducerBuilder, obj interface{}) bool {
+	return p != nil && p.builder == builder && p.typ == reflect.TypeOf(obj) &&
+		p.generation == atomic.LoadUint32(&builder.generation)
+}
+
+// columns 返回列名对应的列 与上一次的列名相同时复用 返回的slice不可修改
+func (p *ScanPlan) columns(names []string) []Column {
+	if last, ok := p.last.Load().(*planColumns); ok && equalNames(last.names, names) {
+		return last.columns
+	}
+	columns := p.builder.GetColumns(p.info, names)
+	p.last.Store(&planColumns{names: append([]string(nil), names...), columns: columns})
+	return columns
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package internel
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestRowsScanner_Plan(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	scanner := NewRowsScanner(NewTypeFieldProducer(SnakeMapper))
+	plan, err := scanner.Plan(&[]testRowsStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := func(columns []string, values ...driver.Value) []testRowsStruct {
+		mock.ExpectQuery("query").WillReturnRows(sqlmock.NewRows(columns).AddRow(values...))
+		rows, err := db.QueryContext(context.Background(), "query")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list []testRowsStruct
+		if err := scanner.Scan(rows, &Planned{Plan: plan, Ptr: &list}); err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	// 列名相同时复用上一次的列 列名变化时重新计算
+	if list := query([]string{"a", "c"}, "1", 1); list[0].A != "1" || list[0].C != 1 {
+		t.Fatal(list)
+	}
+	first := plan.last.Load().(*planColumns)
+	if list := query([]string{"a", "c"}, "2", 2); list[0].A != "2" || list[0].C != 2 {
+		t.Fatal(list)
+	}
+	if plan.last.Load().(*planColumns) != first {
+		t.Fatal("columns are not reused")
+	}
+	if list := query([]string{"c", "b"}, 3, "3"); list[0].B != "3" || list[0].C != 3 {
+		t.Fatal(list)
+	}
+
+	// 其他扫描器创建的计划与修改Mapper后的计划被忽略
+	if plan.usableBy(GlobalScanner.builder, &[]testRowsStruct{}) {
+		t.Fatal("plan of another scanner is used")
+	}
+	if plan.usableBy(scanner.builder, &testRowsStruct{}) {
+		t.Fatal("plan of another type is used")
+	}
+	scanner.SetMapper(SnakeMapper)
+	if plan.usableBy(scanner.builder, &[]testRowsStruct{}) {
+		t.Fatal("stale plan is used")
+	}
+	if list := query([]string{"a"}, "4"); list[0].A != "4" {
+		t.Fatal(list)
+	}
+}
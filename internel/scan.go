@@ -1,12 +1,16 @@
 package internel
 
-import "database/sql"
+import (
+	"database/sql"
+	"sync/atomic"
+)
 
 type SQLRows interface {
 	Next() bool
 	Columns() ([]string, error)
 	Scan(dest ...interface{}) error
 	Close() error
+	Err() error
 }
 
 var GlobalTypeFieldProducer = NewTypeFieldProducer(SnakeMapper)
@@ -19,12 +23,34 @@ func Scan(rows SQLRows, ptr interface{}) (err error) {
 	return GlobalScanner.Scan(rows, ptr)
 }
 
+// NewRowsScanner 创建使用fieldProducer映射列与属性的扫描器
+func NewRowsScanner(fieldProducer *TypeFieldProducer) *RowsScanner {
+	return &RowsScanner{
+		builder: NewValuesProducerBuilder(fieldProducer),
+	}
+}
+
 type RowsScanner struct {
 	builder *ValuesProducerBuilder
+	strict  Strictness
+}
+
+// SetStrictness 设置扫描到结构体时的严格程度 默认为StrictNone
+func (rs *RowsScanner) SetStrictness(strict Strictness) {
+	rs.strict = strict
+}
+
+// WithStrictness 返回使用strict的扫描器 与原扫描器共享映射与缓存
+func (rs *RowsScanner) WithStrictness(strict Strictness) *RowsScanner {
+	return &RowsScanner{
+		builder: rs.builder,
+		strict:  strict,
+	}
 }
 
 func (rs *RowsScanner) SetMapper(mapper Mapper) {
 	rs.builder.fieldProducer.Mapper = mapper
+	atomic.AddUint32(&rs.builder.generation, 1)
 }
 
 func (rs *RowsScanner) Scan(rows SQLRows, ptr interface{}) (err error) {
@@ -33,24 +59,34 @@ func (rs *RowsScanner) Scan(rows SQLRows, ptr interface{}) (err error) {
 		rows.Close()
 		return
 	}
-	producer, oneLine, err := rs.builder.Build(ptr, cols)
+	producer, oneLine, err := rs.builder.build(ptr, cols, rows, rs.strict)
 	if err != nil {
 		rows.Close()
 		return
 	}
 	rowsSize := 0
 	for rows.Next() {
-		rowsSize++
 		err = rows.Scan(producer.Values()...)
 		if err != nil {
 			rows.Close()
-			return
+			return wrapScanError(err, rowsSize, cols, producer)
 		}
+		rowsSize++
 		if oneLine {
 			break
 		}
 	}
-	rows.Close()
+	// 遍历因为网络中断 context取消等原因提前结束时 Next返回false 需要通过Err获取错误
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return
+	}
+	if err = rows.Close(); err != nil {
+		return
+	}
+	if flusher, ok := producer.(Flusher); ok {
+		flusher.Flush()
+	}
 	if oneLine && rowsSize == 0 {
 		return sql.ErrNoRows
 	}
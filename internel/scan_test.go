@@ -2,6 +2,9 @@ package internel
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"reflect"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"testing"
 )
@@ -198,6 +201,10 @@ func (*testReadOnlyRows) Close() error {
 	return nil
 }
 
+func (*testReadOnlyRows) Err() error {
+	return nil
+}
+
 /**
 go test -bench ^BenchmarkRowsScanner_Scan$
 	goos: linux
@@ -249,3 +256,136 @@ func BenchmarkRowsScanner_Scan3(b *testing.B) {
 		}
 	})
 }
+
+func TestRowsScanner_ScanMap(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery("query").WillReturnRows(
+		sqlmock.NewRows([]string{"a", "b", "c"}).
+			AddRow([]byte("x"), int64(1), nil).
+			AddRow([]byte("y"), int64(2), 1.5),
+	)
+	rows, _ := db.QueryContext(context.Background(), "query")
+	var list []map[string]interface{}
+	if err := GlobalScanner.Scan(rows, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0]["a"] != "x" || list[0]["b"] != int64(1) || list[1]["c"] != 1.5 {
+		t.Fatal(list)
+	}
+	if v, ok := list[0]["c"]; !ok || v != nil {
+		t.Fatal(list[0])
+	}
+
+	mock.ExpectQuery("query").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("z"))
+	rows, _ = db.QueryContext(context.Background(), "query")
+	var row map[string]interface{}
+	if err := GlobalScanner.Scan(rows, &row); err != nil {
+		t.Fatal(err)
+	}
+	if row["a"] != "z" {
+		t.Fatal(row)
+	}
+}
+
+func TestMapColumn_convert(t *testing.T) {
+	c := &MapColumn{Name: "a", scanType: reflect.TypeOf(sql.NullInt64{})}
+	if v, err := c.convert([]byte("12")); err != nil || v != int64(12) {
+		t.Fatal(v, err)
+	}
+	c = &MapColumn{Name: "a", binary: true}
+	if v, err := c.convert([]byte("12")); err != nil || string(v.([]byte)) != "12" {
+		t.Fatal(v, err)
+	}
+	c = &MapColumn{Name: "a", scanType: timeType}
+	if v, err := c.convert([]byte("2020-01-01")); err != nil || v != "2020-01-01" {
+		t.Fatal(v, err)
+	}
+}
+
+type testKeyedStruct struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name"`
+	Team string `db:"team"`
+}
+
+func TestRowsScanner_ScanKeyedMap(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	newRows := func() SQLRows {
+		mock.ExpectQuery("query").WillReturnRows(
+			sqlmock.NewRows([]string{"team", "id", "name"}).
+				AddRow("x", 1, "a").
+				AddRow("y", 2, "b").
+				AddRow("x", 3, "c"),
+		)
+		rows, _ := db.QueryContext(context.Background(), "query")
+		return rows
+	}
+
+	// 默认使用主键列
+	var byID map[int64]*testKeyedStruct
+	if err := GlobalScanner.Scan(newRows(), &byID); err != nil {
+		t.Fatal(err)
+	}
+	if len(byID) != 3 || byID[3].Name != "c" || byID[1].Team != "x" {
+		t.Fatal(byID)
+	}
+
+	// 指定列并分组
+	byTeam := map[string][]testKeyedStruct{}
+	if err := GlobalScanner.Scan(newRows(), &KeyedBy{Column: "team", Ptr: &byTeam}); err != nil {
+		t.Fatal(err)
+	}
+	if len(byTeam) != 2 || len(byTeam["x"]) != 2 || byTeam["x"][1].ID != 3 || byTeam["y"][0].Name != "b" {
+		t.Fatal(byTeam)
+	}
+
+	// 值类型 键相同时后者覆盖前者
+	var byName map[string]testKeyedStruct
+	if err := GlobalScanner.Scan(newRows(), &KeyedBy{Column: "team", Ptr: &byName}); err != nil {
+		t.Fatal(err)
+	}
+	if len(byName) != 2 || byName["x"].ID != 3 {
+		t.Fatal(byName)
+	}
+
+	if err := GlobalScanner.Scan(newRows(), &KeyedBy{Column: "nope", Ptr: &byName}); err == nil {
+		t.Fatal("unknown key column should fail")
+	}
+}
+
+func TestRowsScanner_ScanErrors(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+
+	// 结果集中途出错时不能返回截断的结果
+	mock.ExpectQuery("query").WillReturnRows(
+		sqlmock.NewRows([]string{"a", "c"}).
+			AddRow("1", 1).
+			AddRow("2", 2).
+			RowError(1, errors.New("connection lost")),
+	)
+	rows, _ := db.QueryContext(context.Background(), "query")
+	var list []testRowsStruct
+	if err := GlobalScanner.Scan(rows, &list); err == nil || err.Error() != "connection lost" {
+		t.Fatal(err)
+	}
+
+	// 扫描错误包含行号 列名与属性
+	mock.ExpectQuery("query").WillReturnRows(
+		sqlmock.NewRows([]string{"a", "c"}).
+			AddRow("1", 1).
+			AddRow("2", "x"),
+	)
+	rows, _ = db.QueryContext(context.Background(), "query")
+	err := GlobalScanner.Scan(rows, &list)
+	var scanErr *ColumnScanError
+	if !errors.As(err, &scanErr) || scanErr.Row != 1 || scanErr.Column != "c" || scanErr.Field != "internel.testRowsStruct.C" {
+		t.Fatal(err)
+	}
+
+	// 关闭时的错误
+	mock.ExpectQuery("query").WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow("1").CloseError(errors.New("close failed")))
+	rows, _ = db.QueryContext(context.Background(), "query")
+	if err = GlobalScanner.Scan(rows, &list); err == nil || err.Error() != "close failed" {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+package internel
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// BatchInsert 批量插入拆分出的一条多行插入语句
+type BatchInsert struct {
+	SQL  string
+	Args []interface{}
+	// Key 插入后获取自增主键的方式 为KeyGiven时无需写回主键
+	Key KeySource
+	// Objects 语句中每一行对应的对象指针 顺序与VALUES一致 用于写回主键
+	Objects []interface{}
+}
+
+// PrepareInsertObjects 为批量插入准备语句 objects为 []T []*T 或指向它们的指针
+// 已给出ID与需要自增ID的对象分别生成语句 每条语句的参数个数不超过MaxPlaceholders
+// fillID为false时不回传自增主键 SQL Server 多行语句的 OUTPUT 顺序不确定 只有单行的语句会回传自增主键
+func (s *SQLGenerator) PrepareInsertObjects(objects interface{}, fillID bool) (batches []*BatchInsert, err error) {
+	slice := reflect.ValueOf(objects)
+	for slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array {
+		return nil, &ScanTypeError{Type: reflect.TypeOf(objects)}
+	}
+	if slice.Len() == 0 {
+		return nil, nil
+	}
+	elemType := slice.Type().Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, &ScanTypeError{Type: reflect.TypeOf(objects)}
+	}
+	table, err := s.getTableInfo(elemType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按是否已给出ID分组 组内保持原有顺序
+	var given, generated []reflect.Value
+	for i := 0; i < slice.Len(); i++ {
+		val := slice.Index(i)
+		for val.Kind() == reflect.Ptr {
+			val = val.Elem()
+		}
+		if !val.CanAddr() {
+			copied := reflect.New(val.Type()).Elem()
+			copied.Set(val)
+			val = copied
+		}
+		ok, err := table.idGiven(val)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			given = append(given, val)
+		} else {
+			generated = append(generated, val)
+		}
+	}
+
+	key := KeyGiven
+	var returning *NamedField
+	if fillID && table.IDField != nil {
+		key = KeyLastInsertID
+		if s.dialect.Returning() != ReturningNone {
+			key = KeyReturning
+			returning = table.IDField
+		}
+	}
+	batches, err = s.chunkInsert(table.Name, table.InsertWithID.fieldArgs, given, KeyGiven, nil)
+	if err != nil {
+		return nil, err
+	}
+	more, err := s.chunkInsert(table.Name, table.Insert.fieldArgs, generated, key, returning)
+	if err != nil {
+		return nil, err
+	}
+	return append(batches, more...), nil
+}
+
+// chunkInsert 将values按参数个数上限拆分为多条插入语句
+func (s *SQLGenerator) chunkInsert(table string, fields []*NamedField, values []reflect.Value, key KeySource, returning *NamedField) (batches []*BatchInsert, err error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	size := len(values)
+	if len(fields) > 0 && s.maxPlaceholders > 0 {
+		size = s.maxPlaceholders / len(fields)
+		if size == 0 {
+			size = 1
+		}
+	}
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+		batchKey, batchReturning := key, returning
+		// SQL Server 不保证多行 OUTPUT INSERTED 的顺序 无法按位置对应对象 因此不回传主键
+		if key == KeyReturning && s.dialect.Returning() == ReturningOutput && end-start > 1 {
+			batchKey, batchReturning = KeyGiven, nil
+		}
+		batch := &BatchInsert{
+			SQL:     GenerateBatchInsertSQL(s.dialect, table, fields, end-start, batchReturning),
+			Args:    make([]interface{}, 0, len(fields)*(end-start)),
+			Key:     batchKey,
+			Objects: make([]interface{}, 0, end-start),
+		}
+		for _, val := range values[start:end] {
+			args, err := fieldsToArgs(val, fields)
+			if err != nil {
+				return nil, err
+			}
+			batch.Args = append(batch.Args, args...)
+			batch.Objects = append(batch.Objects, val.Addr().Interface())
+		}
+		batches = append(batches, batch)
+	}
+	return
+}
+
+// GenerateBatchInsertSQL 生成rows行的多行插入语句 returning不为nil时通过 RETURNING/OUTPUT 回传该列
+func GenerateBatchInsertSQL(d Dialect, table string, fields []*NamedField, rows int, returning *NamedField) string {
+	buf := bytes.NewBuffer([]byte("INSERT INTO "))
+	buf.WriteString(d.Quote(table))
+	buf.WriteString(" (")
+	for i, field := range fields {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(d.Quote(field.Name))
+	}
+	buf.WriteString(")")
+	if returning != nil && d.Returning() == ReturningOutput {
+		buf.WriteString(" OUTPUT INSERTED.")
+		buf.WriteString(d.Quote(returning.Name))
+	}
+	buf.WriteString(" VALUES ")
+	n := 0
+	for row := 0; row < rows; row++ {
+		if row != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('(')
+		for i := range fields {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			n++
+			buf.WriteString(d.Placeholder(n))
+		}
+		buf.WriteByte(')')
+	}
+	if returning != nil && d.Returning() == ReturningClause {
+		buf.WriteString(" RETURNING ")
+		buf.WriteString(d.Quote(returning.Name))
+	}
+	return buf.String()
+}
@@ -0,0 +1,72 @@
+package internel
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSQLGenerator_PrepareInsertObjects(t *testing.T) {
+	type BatchUser struct {
+		ID   int64
+		Name string
+		Age  int
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	sg.SetMaxPlaceholders(5)
+
+	users := []BatchUser{{Name: "a", Age: 1}, {ID: 9, Name: "b", Age: 2}, {Name: "c", Age: 3}, {Name: "d", Age: 4}}
+	batches, err := sg.PrepareInsertObjects(users, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 3 {
+		t.Fatal(len(batches))
+	}
+	// 已给出ID的对象单独插入 每条语句最多5个参数
+	if batches[0].SQL != "INSERT INTO `batch_user` (`id`,`age`,`name`) VALUES (?,?,?)" || batches[0].Key != KeyGiven {
+		t.Fatal(batches[0].SQL, batches[0].Key)
+	}
+	if batches[1].SQL != "INSERT INTO `batch_user` (`age`,`name`) VALUES (?,?),(?,?)" || batches[1].Key != KeyLastInsertID {
+		t.Fatal(batches[1].SQL, batches[1].Key)
+	}
+	if !reflect.DeepEqual(batches[1].Args, []interface{}{1, "a", 3, "c"}) {
+		t.Fatal(batches[1].Args)
+	}
+	if batches[2].SQL != "INSERT INTO `batch_user` (`age`,`name`) VALUES (?,?)" {
+		t.Fatal(batches[2].SQL)
+	}
+	// Objects 指向原slice的元素
+	batches[1].Objects[1].(*BatchUser).ID = 100
+	if users[2].ID != 100 {
+		t.Fatal(users[2])
+	}
+
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), Postgres)
+	batches, err = sg.PrepareInsertObjects(&[]*BatchUser{{Name: "a"}, {Name: "b"}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches[0].SQL != `INSERT INTO "batch_user" ("age","name") VALUES ($1,$2),($3,$4) RETURNING "id"` || batches[0].Key != KeyReturning {
+		t.Fatal(batches[0].SQL)
+	}
+	batches, _ = sg.PrepareInsertObjects([]*BatchUser{{Name: "a"}}, false)
+	if batches[0].SQL != `INSERT INTO "batch_user" ("age","name") VALUES ($1,$2)` || batches[0].Key != KeyGiven {
+		t.Fatal(batches[0].SQL)
+	}
+
+	// SQL Server 多行 OUTPUT 顺序不确定 多行语句不回传主键 单行语句仍然回传
+	sg = NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), SQLServer)
+	sg.SetMaxPlaceholders(4)
+	batches, _ = sg.PrepareInsertObjects([]*BatchUser{{Name: "a"}, {Name: "b"}, {Name: "c"}}, true)
+	if batches[0].SQL != "INSERT INTO [batch_user] ([age],[name]) VALUES (@p1,@p2),(@p3,@p4)" || batches[0].Key != KeyGiven {
+		t.Fatal(batches[0].SQL, batches[0].Key)
+	}
+	if batches[1].SQL != "INSERT INTO [batch_user] ([age],[name]) OUTPUT INSERTED.[id] VALUES (@p1,@p2)" || batches[1].Key != KeyReturning {
+		t.Fatal(batches[1].SQL, batches[1].Key)
+	}
+
+	if _, err := sg.PrepareInsertObjects(BatchUser{}, true); !errors.Is(err, ErrInvalidScanType) {
+		t.Fatal(err)
+	}
+}
@@ -2,39 +2,69 @@ package internel
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
 
+// idColumnName 没有通过pk标签声明主键时 名为id的列视为自增主键
 const idColumnName = "id"
 
-var GlobalSQLGenerator = NewSQLGenerator(GlobalTypeFieldProducer)
+var GlobalSQLGenerator = NewSQLGenerator(GlobalTypeFieldProducer, MySQL)
 
 type SQLGenerator struct {
-	fieldProducer *TypeFieldProducer
-	locker        sync.RWMutex
-	tables        map[reflect.Type]tableInfo
+	fieldProducer   *TypeFieldProducer
+	dialect         Dialect
+	maxPlaceholders int
+	locker          sync.RWMutex
+	tables          map[reflect.Type]tableInfo
+	named           *namedCache
 }
 
-func NewSQLGenerator(fieldProducer *TypeFieldProducer) *SQLGenerator {
+// NewSQLGenerator 创建按照dialect方言生成SQL的生成器 dialect为nil时使用MySQL
+func NewSQLGenerator(fieldProducer *TypeFieldProducer, dialect Dialect) *SQLGenerator {
+	if dialect == nil {
+		dialect = MySQL
+	}
 	sqlGen := &SQLGenerator{
-		fieldProducer: fieldProducer,
-		tables:        map[reflect.Type]tableInfo{},
+		fieldProducer:   fieldProducer,
+		dialect:         dialect,
+		maxPlaceholders: dialect.MaxPlaceholders(),
+		tables:          map[reflect.Type]tableInfo{},
+		named:           newNamedCache(namedCacheSize),
 	}
 	return sqlGen
 }
 
+// SetMaxPlaceholders 设置批量插入时单条语句最多使用的参数个数 超出时拆分为多条语句 默认使用方言的上限
+// 没有加锁 只能在生成器开始使用之前调用 SQLHelper请使用WithMaxPlaceholders
+func (s *SQLGenerator) SetMaxPlaceholders(n int) {
+	s.maxPlaceholders = n
+}
+
+// Dialect 返回生成器使用的方言
+func (s *SQLGenerator) Dialect() Dialect {
+	return s.dialect
+}
+
 // tableInfo 表预生成的信息
 type tableInfo struct {
 	Name         string
-	IDField      *NamedField
+	Keys         []*NamedField        // 主键列 按属性声明顺序排列 没有主键时为空
+	IDField      *NamedField          // 自增列 没有时为nil
+	Version      *NamedField          // 乐观锁版本列 没有时为nil
+	updateSkip   map[*NamedField]bool // 更新时不能SET的主键 自增列与版本列 由完整的列推断
 	Insert       SqlPair
 	InsertWithID SqlPair
 	Update       SqlPair
 	UpdateByID   SqlPair
+	Delete       SqlPair
+	DeleteByID   SqlPair
 	Select       string
+	SelectByID   SqlPair // 参数由调用方按主键顺序给出
 }
 
 type SqlPair struct {
@@ -46,13 +76,29 @@ func (s *SQLGenerator) mapTable(name string, typ reflect.Type) {
 	ti := tableInfo{
 		Name: name,
 	}
+	d := s.dialect
 	fields := toNamedFields(s.fieldProducer.Fields(typ))
-	ti.Insert.sql, ti.IDField, ti.Insert.fieldArgs = GenerateInsertSQL(name, fields, true)
-	ti.InsertWithID.sql, _, ti.InsertWithID.fieldArgs = GenerateInsertSQL(name, fields, false)
-	ti.Update.sql, ti.Update.fieldArgs = GenerateUpdateSQL(name, fields)
-	ti.UpdateByID.sql = ti.Update.sql + " WHERE `" + ti.IDField.Name + "` = ?"
-	ti.UpdateByID.fieldArgs = append(ti.Update.fieldArgs, ti.IDField)
-	ti.Select = GenerateSelectSQL(name, fields)
+	ti.Insert.sql, ti.IDField, ti.Insert.fieldArgs = GenerateInsertSQL(d, name, fields, true)
+	ti.InsertWithID.sql, _, ti.InsertWithID.fieldArgs = GenerateInsertSQL(d, name, fields, false)
+	ti.Keys = primaryKeys(fields)
+	ti.Version = versionField(fields)
+	ti.updateSkip = updateSkip(fields)
+	// 按条件更新时不处理版本列 版本列只在按主键更新时检查并加一
+	ti.Update.sql, ti.Update.fieldArgs = generateUpdateSQL(d, name, fields, ti.updateSkip, nil)
+	if len(ti.Keys) > 0 {
+		conds := ti.updateConditions()
+		updateByID, _ := generateUpdateSQL(d, name, fields, ti.updateSkip, ti.Version)
+		ti.UpdateByID.sql = updateByID + " WHERE " + GenerateKeyCondition(d, conds, len(ti.Update.fieldArgs)+1)
+		ti.UpdateByID.fieldArgs = append(append([]*NamedField{}, ti.Update.fieldArgs...), conds...)
+	}
+	ti.Select = GenerateSelectSQL(d, name, fields)
+	ti.Delete.sql = GenerateDeleteSQL(d, name)
+	if len(ti.Keys) > 0 {
+		ti.DeleteByID.sql = ti.Delete.sql + " WHERE " + GenerateKeyCondition(d, ti.Keys, 1)
+		ti.DeleteByID.fieldArgs = ti.Keys
+		ti.SelectByID.sql = ti.Select + "WHERE " + GenerateKeyCondition(d, ti.Keys, 1)
+		ti.SelectByID.fieldArgs = ti.Keys
+	}
 	s.locker.Lock()
 	s.tables[typ] = ti
 	s.locker.Unlock()
@@ -66,19 +112,84 @@ func (s *SQLGenerator) MapTable(name string, o interface{}) error {
 		typ = typ.Elem()
 	}
 	if typ.Kind() != reflect.Struct {
-		return ErrInvalidScanType
+		return &ScanTypeError{Type: reflect.TypeOf(o)}
 	}
 	s.mapTable(name, typ)
 	return nil
 }
 
+// Fork 创建方言与属性映射相同的生成器 复制当前已关联的表 之后两者的表关联互不影响 用于分表
+func (s *SQLGenerator) Fork() *SQLGenerator {
+	forked := NewSQLGenerator(s.fieldProducer, s.dialect)
+	forked.maxPlaceholders = s.maxPlaceholders
+	s.locker.RLock()
+	for typ, table := range s.tables {
+		forked.tables[typ] = table
+	}
+	s.locker.RUnlock()
+	return forked
+}
+
+// ForkDialect 创建使用dialect方言的生成器 已关联的表保留表名并按新的方言重新生成SQL
+// 参数个数上限使用新方言的默认值
+func (s *SQLGenerator) ForkDialect(dialect Dialect) *SQLGenerator {
+	forked := NewSQLGenerator(s.fieldProducer, dialect)
+	s.locker.RLock()
+	names := make(map[reflect.Type]string, len(s.tables))
+	for typ, table := range s.tables {
+		names[typ] = table.Name
+	}
+	s.locker.RUnlock()
+	for typ, name := range names {
+		forked.mapTable(name, typ)
+	}
+	return forked
+}
+
+// TableName 返回o的类型关联的表名 o为结构体 指向结构体的指针或它们的slice
+func (s *SQLGenerator) TableName(o interface{}) (string, error) {
+	typ := reflect.TypeOf(o)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return "", &ScanTypeError{Type: reflect.TypeOf(o)}
+	}
+	table, err := s.getTableInfo(typ)
+	if err != nil {
+		return "", err
+	}
+	return table.Name, nil
+}
+
+// ColumnValue 返回对象o中映射为column列的属性的值
+func (s *SQLGenerator) ColumnValue(o interface{}, column string) (interface{}, error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return nil, err
+	}
+	field, ok := s.fieldProducer.Fields(val.Type())[column]
+	if !ok {
+		return nil, fmt.Errorf("column %q not found in %s", column, val.Type())
+	}
+	v, err := field.ValueOf(val)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
 func toNamedFields(fields map[string]*Field) (list []*NamedField) {
 	names := make([]string, 0, len(fields))
 	for name := range fields {
 		names = append(names, name)
 	}
+	// 按列名长度排列 长度相同时按属性声明顺序 保证生成的SQL稳定
 	sort.Slice(names, func(i, j int) bool {
-		return len(names[i]) < len(names[j])
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return lessIndex(fields[names[i]].index(), fields[names[j]].index())
 	})
 
 	list = make([]*NamedField, 0, len(names))
@@ -96,9 +207,15 @@ func (s *SQLGenerator) getStructValue(o interface{}) (val reflect.Value, err err
 		val = val.Elem()
 	}
 	if val.Kind() != reflect.Struct {
-		err = ErrInvalidScanType
+		err = &ScanTypeError{Type: reflect.TypeOf(o)}
 		return
 	}
+	// 按值传入的结构体不可寻址 复制一份以便取属性指针
+	if !val.CanAddr() {
+		copied := reflect.New(val.Type()).Elem()
+		copied.Set(val)
+		val = copied
+	}
 	return
 }
 
@@ -123,16 +240,127 @@ func fieldsToArgs(value reflect.Value, fields []*NamedField) (args []interface{}
 	args = make([]interface{}, 0, len(fields)+4)
 	for _, arg := range fields {
 		var argValue reflect.Value
-		argValue, err = arg.PointerOf(value)
+		argValue, err = arg.ValueOf(value)
 		if err != nil {
 			return
 		}
-		args = append(args, argValue.Elem().Interface())
+		args = append(args, argValue.Interface())
 	}
 	return
 }
 
+// PrepareUpdateFields 通过传入的o为按主键更新指定列的操作准备SQL语句与参数 每次调用时生成SQL
+// columns必须为可以更新的列 不能包含主键 columns为空时返回空的sql
+func (s *SQLGenerator) PrepareUpdateFields(o interface{}, columns []string) (sql string, args []interface{}, err error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	fields := make([]*NamedField, 0, len(columns))
+	for _, col := range columns {
+		field := findField(table.Update.fieldArgs, col)
+		if field == nil {
+			err = fmt.Errorf("update: unknown or not updatable column %q", col)
+			return
+		}
+		fields = append(fields, field)
+	}
+	return s.prepareUpdateByID(val, table, fields)
+}
+
+// PrepareUpdateNonZero 通过传入的o为按主键更新非零值列的操作准备SQL语句与参数 每次调用时生成SQL
+// 指针类型的属性不为nil时即视为需要更新 所有列都为零值时返回空的sql
+func (s *SQLGenerator) PrepareUpdateNonZero(o interface{}) (sql string, args []interface{}, err error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	fields := make([]*NamedField, 0, len(table.Update.fieldArgs))
+	for _, field := range table.Update.fieldArgs {
+		var fieldValue reflect.Value
+		fieldValue, err = field.ValueOf(val)
+		if err != nil {
+			return
+		}
+		if !fieldValue.IsZero() {
+			fields = append(fields, field)
+		}
+	}
+	return s.prepareUpdateByID(val, table, fields)
+}
+
+// prepareUpdateByID 生成按主键更新fields的语句
+func (s *SQLGenerator) prepareUpdateByID(val reflect.Value, table tableInfo, fields []*NamedField) (sql string, args []interface{}, err error) {
+	if len(table.Keys) == 0 {
+		err = ErrNoPrimaryKey
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	sql, fields = generateUpdateSQL(s.dialect, table.Name, fields, table.updateSkip, table.Version)
+	conds := table.updateConditions()
+	sql += " WHERE " + GenerateKeyCondition(s.dialect, conds, len(fields)+1)
+	args, err = fieldsToArgs(val, append(fields, conds...))
+	return
+}
+
+// updateConditions 按主键更新时的条件列 有版本列时在主键之后加上版本列
+func (table *tableInfo) updateConditions() []*NamedField {
+	if table.Version == nil {
+		return table.Keys
+	}
+	return append(append([]*NamedField{}, table.Keys...), table.Version)
+}
+
+// Versioned o的类型是否声明了乐观锁版本列
+func (s *SQLGenerator) Versioned(o interface{}) bool {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return false
+	}
+	table, err := s.getTableInfo(val.Type())
+	return err == nil && table.Version != nil
+}
+
+// IncrVersion 将对象的版本属性加一 用于更新成功后与数据库保持一致
+// o必须为指向结构体的指针 否则返回ErrNotAddressable 没有版本列时不做任何修改
+func (s *SQLGenerator) IncrVersion(o interface{}) error {
+	if reflect.ValueOf(o).Kind() != reflect.Ptr {
+		return ErrNotAddressable
+	}
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return err
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil || table.Version == nil {
+		return err
+	}
+	ptr, err := table.Version.PointerOf(val)
+	if err != nil {
+		return err
+	}
+	v := ptr.Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return setIntValue(v, v.Int()+1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setIntValue(v, int64(v.Uint())+1)
+	}
+	return fmt.Errorf("version column %q must be an integer, got %s", table.Version.Name, v.Type())
+}
+
 // PrepareUpdateByID 通过传入的o为UpdateByID操作准备SQL语句与参数。
+// omitempty的列都为零值 没有需要更新的列时返回空的sql
 func (s *SQLGenerator) PrepareUpdateByID(o interface{}) (sql string, args []interface{}, err error) {
 	val, err := s.getStructValue(o)
 	if err != nil {
@@ -142,6 +370,17 @@ func (s *SQLGenerator) PrepareUpdateByID(o interface{}) (sql string, args []inte
 	if err != nil {
 		return
 	}
+	if len(table.Keys) == 0 {
+		err = ErrNoPrimaryKey
+		return
+	}
+	fields, omitted, err := omitZero(val, table.Update.fieldArgs, optOmitEmpty)
+	if err != nil {
+		return
+	}
+	if omitted || len(fields) == 0 {
+		return s.prepareUpdateByID(val, table, fields)
+	}
 	args, err = fieldsToArgs(val, table.UpdateByID.fieldArgs)
 	if err != nil {
 		return
@@ -150,6 +389,7 @@ func (s *SQLGenerator) PrepareUpdateByID(o interface{}) (sql string, args []inte
 }
 
 // PrepareUpdate 通过传入的o为Update操作准备SQL语句与参数。
+// omitempty的列都为零值 没有需要更新的列时返回空的sql 不更新也不检查版本列
 func (s *SQLGenerator) PrepareUpdate(o interface{}) (sql string, args []interface{}, err error) {
 	val, err := s.getStructValue(o)
 	if err != nil {
@@ -159,20 +399,72 @@ func (s *SQLGenerator) PrepareUpdate(o interface{}) (sql string, args []interfac
 	if err != nil {
 		return
 	}
-	args, err = fieldsToArgs(val, table.Update.fieldArgs)
+	sql, fields := table.Update.sql, table.Update.fieldArgs
+	fields, omitted, err := omitZero(val, fields, optOmitEmpty)
 	if err != nil {
 		return
 	}
-	return table.Update.sql, args, nil
+	if len(fields) == 0 {
+		return "", nil, nil
+	}
+	if omitted {
+		sql, fields = generateUpdateSQL(s.dialect, table.Name, fields, table.updateSkip, nil)
+	}
+	args, err = fieldsToArgs(val, fields)
+	if err != nil {
+		return
+	}
+	return sql, args, nil
+}
+
+// omitZero 去掉fields中声明了option选项且值为零值的列 omitted表示是否有列被去掉
+// 没有列被去掉时返回fields本身
+func omitZero(val reflect.Value, fields []*NamedField, option fieldOption) (list []*NamedField, omitted bool, err error) {
+	list = fields
+	for i, field := range fields {
+		omit := false
+		if field.options&option != 0 {
+			var fieldValue reflect.Value
+			fieldValue, err = field.ValueOf(val)
+			if err != nil {
+				return
+			}
+			omit = fieldValue.IsZero()
+		}
+		switch {
+		case omit && !omitted:
+			// 第一次遇到需要去掉的列时才复制 避免没有去掉列时的内存分配
+			omitted = true
+			list = append(make([]*NamedField, 0, len(fields)), fields[:i]...)
+		case !omit && omitted:
+			list = append(list, field)
+		}
+	}
+	return
+}
+
+// scanTarget 去掉KeyedBy与Planned的包装 返回实际的扫描目标
+func scanTarget(o interface{}) interface{} {
+	for {
+		switch target := o.(type) {
+		case *KeyedBy:
+			o = target.Ptr
+		case *Planned:
+			o = target.Ptr
+		default:
+			return o
+		}
+	}
 }
 
 func (s *SQLGenerator) PrepareSelectFrom(o interface{}) (sql string, err error) {
+	o = scanTarget(o)
 	typ := reflect.TypeOf(o)
-	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
 		typ = typ.Elem()
 	}
 	if typ.Kind() != reflect.Struct {
-		err = ErrInvalidScanType
+		err = &ScanTypeError{Type: reflect.TypeOf(o)}
 		return
 	}
 
@@ -184,8 +476,112 @@ func (s *SQLGenerator) PrepareSelectFrom(o interface{}) (sql string, err error)
 	return
 }
 
+// PrepareSelectExpr 为o对应的表准备 SELECT expr FROM `table` 形式的语句 o可以为结构体或结构体的slice
+// 如 expr 为 COUNT(*) 时用于统计行数
+func (s *SQLGenerator) PrepareSelectExpr(o interface{}, expr string) (sql string, err error) {
+	o = scanTarget(o)
+	typ := reflect.TypeOf(o)
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		err = &ScanTypeError{Type: reflect.TypeOf(o)}
+		return
+	}
+	info, err := s.getTableInfo(typ)
+	if err != nil {
+		return
+	}
+	return "SELECT " + expr + " FROM " + s.dialect.Quote(info.Name) + " ", nil
+}
+
+// Rebind 将使用 ? 作为占位符的sql转换为生成器方言的占位符
+func (s *SQLGenerator) Rebind(sql string) string {
+	return Rebind(s.dialect, sql, 1)
+}
+
+// RebindFrom 与Rebind相同 但编号从start开始 用于拼接在已有参数之后的条件
+func (s *SQLGenerator) RebindFrom(sql string, start int) string {
+	return Rebind(s.dialect, sql, start)
+}
+
+// ExpandSliceArgs 按生成器的方言展开sql中下标不小于from的slice参数
+func (s *SQLGenerator) ExpandSliceArgs(sql string, args []interface{}, from int) (string, []interface{}, error) {
+	return ExpandSliceArgs(s.dialect, sql, args, from)
+}
+
+// PrepareDeleteByID 通过传入的o为按主键删除操作准备SQL语句与参数。
+func (s *SQLGenerator) PrepareDeleteByID(o interface{}) (sql string, args []interface{}, err error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	if len(table.Keys) == 0 {
+		err = ErrNoPrimaryKey
+		return
+	}
+	args, err = fieldsToArgs(val, table.DeleteByID.fieldArgs)
+	if err != nil {
+		return
+	}
+	return table.DeleteByID.sql, args, nil
+}
+
+// PrepareDelete 为o对应的表准备不带条件的删除语句 o仅用于确定表
+func (s *SQLGenerator) PrepareDelete(o interface{}) (sql string, err error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	return table.Delete.sql, nil
+}
+
+// PrepareSelectByID 为o对应的表准备按主键查询的语句 ids按主键声明顺序给出
+func (s *SQLGenerator) PrepareSelectByID(o interface{}, ids []interface{}) (sql string, args []interface{}, err error) {
+	val, err := s.getStructValue(scanTarget(o))
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	if len(table.Keys) == 0 {
+		err = ErrNoPrimaryKey
+		return
+	}
+	if len(ids) != len(table.SelectByID.fieldArgs) {
+		err = fmt.Errorf("expect %d primary key values, got %d", len(table.SelectByID.fieldArgs), len(ids))
+		return
+	}
+	return table.SelectByID.sql, ids, nil
+}
+
 // PrepareInsert 通过传入的o为Insert操作准备SQL语句与参数。
 func (s *SQLGenerator) PrepareInsert(o interface{}) (sql string, args []interface{}, err error) {
+	sql, args, _, err = s.PrepareInsertObject(o)
+	return
+}
+
+// KeySource 插入后获取主键的方式
+type KeySource int
+
+const (
+	KeyGiven        KeySource = iota // 主键由对象给出 无需获取
+	KeyLastInsertID                  // 通过 sql.Result 的 LastInsertId 获取
+	KeyReturning                     // 语句通过 RETURNING/OUTPUT 返回主键 需要使用Query执行
+)
+
+// PrepareInsertObject 通过传入的o为Insert操作准备SQL语句与参数 并给出插入后获取自增主键的方式
+func (s *SQLGenerator) PrepareInsertObject(o interface{}) (sql string, args []interface{}, key KeySource, err error) {
 	val, err := s.getStructValue(o)
 	if err != nil {
 		return
@@ -195,21 +591,209 @@ func (s *SQLGenerator) PrepareInsert(o interface{}) (sql string, args []interfac
 		return
 	}
 	pair := table.Insert
+	key = KeyLastInsertID
+	if s.dialect.Returning() != ReturningNone {
+		key = KeyReturning
+	}
+	if table.IDField == nil {
+		key = KeyGiven
+	}
 	// 检查是否需要插入ID
+	given, err := table.idGiven(val)
+	if err != nil {
+		return
+	}
+	if given {
+		pair = table.InsertWithID
+		key = KeyGiven
+	}
+	sql, fields := pair.sql, pair.fieldArgs
+	fields, omitted, err := omitZero(val, fields, optOmitEmpty|optDefault)
+	if err != nil {
+		return
+	}
+	if omitted {
+		var returning *NamedField
+		if key == KeyReturning {
+			returning = table.IDField
+		}
+		sql = GenerateBatchInsertSQL(s.dialect, table.Name, fields, 1, returning)
+	}
+	args, err = fieldsToArgs(val, fields)
+	return sql, args, key, err
+}
+
+// idGiven 对象的自增属性是否已经给出 已给出时插入语句需要包含该列 没有自增列时视为已给出
+func (table *tableInfo) idGiven(val reflect.Value) (bool, error) {
+	if table.IDField == nil {
+		return true, nil
+	}
 	idVal, err := table.IDField.PointerOf(val)
+	if err != nil {
+		return false, err
+	}
 	idVal = idVal.Elem()
 	switch idVal.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.String:
+		return !idVal.IsZero(), nil
+	}
+	return false, nil
+}
+
+// PrepareUpsert 通过传入的o为插入或更新操作准备SQL语句与参数
+// conflict为判断冲突的唯一键列 为空时使用ID列 MySQL由表的唯一索引决定冲突 忽略该参数
+// update为冲突时需要更新的列 为nil时更新除冲突列与ID以外所有插入的列
+func (s *SQLGenerator) PrepareUpsert(o interface{}, conflict, update []string) (sql string, args []interface{}, err error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	pair := table.Insert
+	given, err := table.idGiven(val)
+	if err != nil {
+		return
+	}
+	if given {
+		pair = table.InsertWithID
+	}
+	fields, _, err := omitZero(val, pair.fieldArgs, optOmitEmpty|optDefault)
+	if err != nil {
+		return
+	}
+	if len(conflict) == 0 {
+		for _, key := range table.Keys {
+			conflict = append(conflict, key.Name)
+		}
+	}
+	if update == nil {
+		// 只更新插入了的且可以更新的列 跳过主键 insertonly 与冲突列
+		skip := map[string]bool{}
+		for _, col := range conflict {
+			skip[col] = true
+		}
+		for _, field := range fields {
+			if !skip[field.Name] && findField(table.Update.fieldArgs, field.Name) != nil {
+				update = append(update, field.Name)
+			}
+		}
+	} else {
+		for _, col := range update {
+			if findField(fields, col) == nil {
+				err = fmt.Errorf("upsert: unknown column %q", col)
+				return
+			}
+		}
+	}
+	clause, err := s.dialect.OnConflict(conflict, update)
+	if err != nil {
+		return
+	}
+	args, err = fieldsToArgs(val, fields)
+	if err != nil {
+		return
+	}
+	sql = GenerateBatchInsertSQL(s.dialect, table.Name, fields, 1, nil) + " " + clause
+	return
+}
+
+func findField(fields []*NamedField, name string) *NamedField {
+	for _, field := range fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// GetInsertID 读取对象的整数主键 没有自增列时使用唯一的主键列
+// 没有自增列与主键 联合主键或非整数类型的主键返回0
+func (s *SQLGenerator) GetInsertID(o interface{}) (int64, error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return 0, err
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return 0, err
+	}
+	field := table.IDField
+	if field == nil && len(table.Keys) == 1 {
+		field = table.Keys[0]
+	}
+	if field == nil {
+		return 0, nil
+	}
+	idVal, err := field.ValueOf(val)
+	if err != nil {
+		return 0, err
+	}
+	switch idVal.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if idVal.Int() != 0 {
-			pair = table.InsertWithID
+		return idVal.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(idVal.Uint()), nil
+	}
+	return 0, nil
+}
+
+// SetInsertID 将插入后得到的自增主键写回对象的ID属性 支持整数与字符串类型的ID
+// o必须为指向结构体的指针 否则返回ErrNotAddressable
+func (s *SQLGenerator) SetInsertID(o interface{}, id int64) error {
+	if reflect.ValueOf(o).Kind() != reflect.Ptr {
+		return ErrNotAddressable
+	}
+	idVal, err := s.idValue(o)
+	if err != nil {
+		return err
+	}
+	return setIntValue(idVal, id)
+}
+
+// setIntValue 将整数转换为v的类型后写入v
+func setIntValue(v reflect.Value, i int64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, v.Type())
 		}
+		v.SetInt(i)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if idVal.Uint() != 0 {
-			pair = table.InsertWithID
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows %s", i, v.Type())
 		}
+		v.SetUint(uint64(i))
+	case reflect.String:
+		v.SetString(strconv.FormatInt(i, 10))
+	default:
+		return fmt.Errorf("cannot assign value %d to %s", i, v.Type())
 	}
-	args, err = fieldsToArgs(val, pair.fieldArgs)
-	return pair.sql, args, nil
+	return nil
+}
+
+// idValue 获取对象ID属性的值
+func (s *SQLGenerator) idValue(o interface{}) (idVal reflect.Value, err error) {
+	val, err := s.getStructValue(o)
+	if err != nil {
+		return
+	}
+	table, err := s.getTableInfo(val.Type())
+	if err != nil {
+		return
+	}
+	if table.IDField == nil {
+		err = ErrNoPrimaryKey
+		return
+	}
+	idVal, err = table.IDField.PointerOf(val)
+	if err != nil {
+		return
+	}
+	return idVal.Elem(), nil
 }
 
 type NamedField struct {
@@ -217,46 +801,76 @@ type NamedField struct {
 	Field
 }
 
-func GenerateInsertSQL(table string, fields []*NamedField, skipID bool) (sql string, id *NamedField, list []*NamedField) {
-	buf := bytes.NewBuffer([]byte("INSERT INTO `"))
-	buf.WriteString(table)
-	buf.WriteString("` (")
+// GenerateInsertSQL 生成插入语句 skipID时跳过自增列 方言支持时通过 RETURNING/OUTPUT 回传自增的值
+func GenerateInsertSQL(d Dialect, table string, fields []*NamedField, skipID bool) (sql string, id *NamedField, list []*NamedField) {
+	buf := bytes.NewBuffer([]byte("INSERT INTO "))
+	buf.WriteString(d.Quote(table))
+	buf.WriteString(" (")
+	if skipID {
+		id = autoIncrement(fields)
+	}
 	i := 0
 	for _, field := range fields {
-		if skipID {
-			if strings.ToLower(field.Name) == idColumnName {
-				id = field
-				continue
-			}
+		if field == id || field.options&optReadOnly != 0 {
+			continue
 		}
 		list = append(list, field)
 		if i != 0 {
 			buf.WriteByte(',')
 		}
-		buf.WriteByte('`')
-		buf.WriteString(field.Name)
-		buf.WriteByte('`')
+		buf.WriteString(d.Quote(field.Name))
 		i++
 	}
-	buf.WriteString(") VALUES (")
+	buf.WriteString(")")
+	if id != nil && d.Returning() == ReturningOutput {
+		buf.WriteString(" OUTPUT INSERTED.")
+		buf.WriteString(d.Quote(id.Name))
+	}
+	buf.WriteString(" VALUES (")
 
-	for ; i > 0; i-- {
-		buf.WriteByte('?')
-		if i != 1 {
+	for n := 1; n <= i; n++ {
+		if n != 1 {
 			buf.WriteByte(',')
 		}
+		buf.WriteString(d.Placeholder(n))
 	}
 	buf.WriteString(`)`)
+	if id != nil && d.Returning() == ReturningClause {
+		buf.WriteString(" RETURNING ")
+		buf.WriteString(d.Quote(id.Name))
+	}
 	return buf.String(), id, list
 }
 
-func GenerateUpdateSQL(table string, fields []*NamedField) (sql string, list []*NamedField) {
-	buf := bytes.NewBuffer([]byte("UPDATE `"))
-	buf.WriteString(table)
-	buf.WriteString("` SET ")
+// GenerateUpdateSQL 生成更新fields的语句 fields必须为表的完整列 主键 自增列与版本列由fields推断
+func GenerateUpdateSQL(d Dialect, table string, fields []*NamedField) (sql string, list []*NamedField) {
+	return generateUpdateSQL(d, table, fields, updateSkip(fields), versionField(fields))
+}
+
+// updateSkip 从表的完整列中推断更新时不能SET的主键 自增列与版本列
+// 只有部分列时无法推断 如pk声明的主键不在其中时会把名为id的普通列当作主键
+func updateSkip(fields []*NamedField) map[*NamedField]bool {
+	skip := map[*NamedField]bool{}
+	for _, key := range primaryKeys(fields) {
+		skip[key] = true
+	}
+	if id := autoIncrement(fields); id != nil {
+		skip[id] = true
+	}
+	if version := versionField(fields); version != nil {
+		skip[version] = true
+	}
+	return skip
+}
+
+// generateUpdateSQL 生成更新fields中除skip以外的列的语句 version不为nil时将版本列加一
+func generateUpdateSQL(d Dialect, table string, fields []*NamedField, skip map[*NamedField]bool, version *NamedField) (sql string, list []*NamedField) {
+	buf := bytes.NewBuffer([]byte("UPDATE "))
+	buf.WriteString(d.Quote(table))
+	buf.WriteString(" SET ")
 	i := 0
 	for _, field := range fields {
-		if strings.ToLower(field.Name) == idColumnName {
+		if skip[field] || field.options&(optReadOnly|optInsertOnly) != 0 {
 			continue
 		}
 		list = append(list, field)
@@ -264,29 +878,111 @@ func GenerateUpdateSQL(table string, fields []*NamedField) (sql string, list []*
 		if i != 0 {
 			buf.WriteByte(',')
 		}
-		buf.WriteByte('`')
-		buf.WriteString(field.Name)
-		buf.WriteString("`=?")
 		i++
+		buf.WriteString(d.Quote(field.Name))
+		buf.WriteByte('=')
+		buf.WriteString(d.Placeholder(i))
+	}
+	if version != nil {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(d.Quote(version.Name))
+		buf.WriteByte('=')
+		buf.WriteString(d.Quote(version.Name))
+		buf.WriteString("+1")
 	}
 	return buf.String(), list
 }
 
-func GenerateSelectSQL(table string, fields []*NamedField) (sql string) {
+// versionField 获取version标签声明的乐观锁版本列 没有时返回nil
+func versionField(fields []*NamedField) *NamedField {
+	for _, field := range fields {
+		if field.options&optVersion != 0 {
+			return field
+		}
+	}
+	return nil
+}
+
+// GenerateKeyCondition 生成主键条件 `a` = ? AND `b` = ? 占位符从第start个参数开始编号
+func GenerateKeyCondition(d Dialect, keys []*NamedField, start int) string {
+	conds := make([]string, 0, len(keys))
+	for i, key := range keys {
+		conds = append(conds, d.Quote(key.Name)+" = "+d.Placeholder(start+i))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// primaryKeys 获取主键列 优先使用pk标签声明的列 按属性声明顺序排列 没有声明时使用名为id的列
+func primaryKeys(fields []*NamedField) (keys []*NamedField) {
+	for _, field := range fields {
+		if field.options&optPrimaryKey != 0 {
+			keys = append(keys, field)
+		}
+	}
+	if len(keys) == 0 {
+		for _, field := range fields {
+			if strings.ToLower(field.Name) == idColumnName {
+				return []*NamedField{field}
+			}
+		}
+		return nil
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return lessIndex(keys[i].index(), keys[j].index())
+	})
+	return
+}
+
+// autoIncrement 获取自增列 优先使用autoincr标签声明的列 没有声明任何pk与autoincr时使用名为id的列
+func autoIncrement(fields []*NamedField) *NamedField {
+	declared := false
+	for _, field := range fields {
+		if field.options&optAutoIncrement != 0 {
+			return field
+		}
+		if field.options&optPrimaryKey != 0 {
+			declared = true
+		}
+	}
+	if declared {
+		return nil
+	}
+	for _, field := range fields {
+		if strings.ToLower(field.Name) == idColumnName {
+			return field
+		}
+	}
+	return nil
+}
+
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func GenerateDeleteSQL(d Dialect, table string) (sql string) {
+	return "DELETE FROM " + d.Quote(table)
+}
+
+func GenerateSelectSQL(d Dialect, table string, fields []*NamedField) (sql string) {
 	buf := bytes.NewBuffer([]byte("SELECT "))
 	i := 0
 	for _, field := range fields {
 		if i != 0 {
 			buf.WriteByte(',')
 		}
-		buf.WriteByte('`')
-		buf.WriteString(field.Name)
-		buf.WriteByte('`')
+		buf.WriteString(d.Quote(field.Name))
 		i++
 	}
-	buf.WriteString(" FROM `")
-	buf.WriteString(table)
-	buf.WriteString("` ")
+	buf.WriteString(" FROM ")
+	buf.WriteString(d.Quote(table))
+	buf.WriteString(" ")
 	return buf.String()
 }
 
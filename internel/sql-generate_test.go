@@ -1,6 +1,7 @@
 package internel
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -19,13 +20,13 @@ func TestGenerateInsertSQL(t *testing.T) {
 	}
 	fields := toNamedFields(Fields(reflect.TypeOf(&testStruct{}), SnakeMapper))
 
-	sql, _, _ := GenerateInsertSQL("table", fields, true)
+	sql, _, _ := GenerateInsertSQL(MySQL, "table", fields, true)
 	if sql != "INSERT INTO `table` (`name`) VALUES (?)" {
 		t.Fatal(sql)
 	}
 
 	fields = toNamedFields(Fields(reflect.TypeOf(&testStruct2{}), SnakeMapper))
-	sql, _, list := GenerateInsertSQL("table", fields, true)
+	sql, _, list := GenerateInsertSQL(MySQL, "table", fields, true)
 	if sql != "INSERT INTO `table` (`name`,`show_name`,`created_time`) VALUES (?,?,?)" {
 		t.Fatal(sql)
 	}
@@ -34,7 +35,7 @@ func TestGenerateInsertSQL(t *testing.T) {
 	}
 
 	fields = toNamedFields(Fields(reflect.TypeOf(&testStruct2{}), SnakeMapper))
-	sql, _, list = GenerateInsertSQL("table", fields, false)
+	sql, _, list = GenerateInsertSQL(MySQL, "table", fields, false)
 	if sql != "INSERT INTO `table` (`id`,`name`,`show_name`,`created_time`) VALUES (?,?,?,?)" {
 		t.Fatal(sql)
 	}
@@ -53,7 +54,7 @@ func TestGenerateSelectSQL(t *testing.T) {
 	}
 	fields := toNamedFields(Fields(reflect.TypeOf(&testStruct{}), SnakeMapper))
 
-	sql:= GenerateSelectSQL("table", fields)
+	sql := GenerateSelectSQL(MySQL, "table", fields)
 	if sql != "SELECT `id`,`name`,`show_name`,`created_time` FROM `table` " {
 		t.Fatal(sql)
 	}
@@ -72,13 +73,13 @@ func TestGenerateUpdateSQL(t *testing.T) {
 	}
 	fields := toNamedFields(Fields(reflect.TypeOf(&testStruct{}), SnakeMapper))
 
-	sql, _ := GenerateUpdateSQL("table", fields)
+	sql, _ := GenerateUpdateSQL(MySQL, "table", fields)
 	if sql != "UPDATE `table` SET `name`=?" {
 		t.Fatal(sql)
 	}
 
 	fields = toNamedFields(Fields(reflect.TypeOf(&testStruct2{}), SnakeMapper))
-	sql, list := GenerateUpdateSQL("table", fields)
+	sql, list := GenerateUpdateSQL(MySQL, "table", fields)
 	if sql != "UPDATE `table` SET `name`=?,`show_name`=?,`created_time`=?" {
 		t.Fatal(sql)
 	}
@@ -94,7 +95,7 @@ func TestSQLGenerator_MapTable(t *testing.T) {
 		ShowName    string
 		CreatedTime time.Time
 	}
-	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper))
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
 	sg.MapTable("not_type_name", testStruct2{})
 	ts := &testStruct2{
 		Name:        "thisisname",
@@ -109,6 +110,34 @@ func TestSQLGenerator_MapTable(t *testing.T) {
 	}
 }
 
+func TestSQLGenerator_Fork(t *testing.T) {
+	type order struct {
+		ID     int
+		UserID int64
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	name, err := sg.TableName([]*order{})
+	if err != nil || name != "order" {
+		t.Fatal(name, err)
+	}
+	forked := sg.Fork()
+	forked.MapTable(name+"_07", order{})
+	if name, _ := sg.TableName(order{}); name != "order" {
+		t.Fatal(name)
+	}
+	sql, _, _ := forked.PrepareInsert(&order{})
+	if sql != "INSERT INTO `order_07` (`user_id`) VALUES (?)" {
+		t.Fatal(sql)
+	}
+	v, err := forked.ColumnValue(&order{UserID: 7}, "user_id")
+	if err != nil || v != int64(7) {
+		t.Fatal(v, err)
+	}
+	if _, err := forked.ColumnValue(order{}, "missing"); err == nil {
+		t.Fatal("expect error")
+	}
+}
+
 func TestSQLGenerator_PrepareInsert(t *testing.T) {
 	type ThisUseTypeName struct {
 		Id          int
@@ -120,7 +149,7 @@ func TestSQLGenerator_PrepareInsert(t *testing.T) {
 		Name:        "thisisname",
 		CreatedTime: time.Now(),
 	}
-	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper))
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
 	sql, args, _ := sg.PrepareInsert(ts)
 	if sql != "INSERT INTO `this_use_type_name` (`name`,`show_name`,`created_time`) VALUES (?,?,?)" {
 		t.Fatal(sql)
@@ -150,7 +179,7 @@ func TestSQLGenerator_PrepareUpdate(t *testing.T) {
 		Name:        "thisisname",
 		CreatedTime: time.Now(),
 	}
-	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper))
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
 	sql, args, _ := sg.PrepareUpdate(ts)
 	if sql != "UPDATE `this_use_type_name` SET `name`=?,`show_name`=?,`created_time`=?" {
 		t.Fatal(sql)
@@ -171,7 +200,7 @@ func TestSQLGenerator_PrepareUpdateByID(t *testing.T) {
 		Name:        "thisisname",
 		CreatedTime: time.Now(),
 	}
-	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper))
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
 	sql, args, _ := sg.PrepareUpdate(ts)
 	if sql != "UPDATE `this_use_type_name` SET `name`=?,`show_name`=?,`created_time`=?" {
 		t.Fatal(sql)
@@ -181,6 +210,48 @@ func TestSQLGenerator_PrepareUpdateByID(t *testing.T) {
 	}
 }
 
+func TestSQLGenerator_Version(t *testing.T) {
+	type versioned struct {
+		ID      int
+		Name    string
+		Rev     uint32 `db:"rev,version"`
+		Comment string
+	}
+	v := &versioned{ID: 1, Name: "a", Rev: 3}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), Postgres)
+	sql, args, _ := sg.PrepareUpdateByID(v)
+	if sql != `UPDATE "versioned" SET "name"=$1,"comment"=$2,"rev"="rev"+1 WHERE "id" = $3 AND "rev" = $4` {
+		t.Fatal(sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a", "", 1, uint32(3)}) {
+		t.Fatal(args)
+	}
+	sql, args, _ = sg.PrepareUpdateFields(v, []string{"comment"})
+	if sql != `UPDATE "versioned" SET "comment"=$1,"rev"="rev"+1 WHERE "id" = $2 AND "rev" = $3` {
+		t.Fatal(sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"", 1, uint32(3)}) {
+		t.Fatal(args)
+	}
+	if _, _, err := sg.PrepareUpdateFields(v, []string{"rev"}); err == nil {
+		t.Fatal("version column should not be updatable")
+	}
+	sql, _, _ = sg.PrepareUpdate(v)
+	// 按条件更新不处理版本列
+	if sql != `UPDATE "versioned" SET "name"=$1,"comment"=$2` {
+		t.Fatal(sql)
+	}
+	if !sg.Versioned(v) || sg.Versioned(&struct{ ID int }{}) {
+		t.Fatal("versioned")
+	}
+	if err := sg.IncrVersion(v); err != nil || v.Rev != 4 {
+		t.Fatal(v, err)
+	}
+	if err := sg.IncrVersion(*v); err != ErrNotAddressable {
+		t.Fatal(err)
+	}
+}
+
 func BenchmarkSQLGenerator_PrepareInsert(b *testing.B) {
 	type ThisUseTypeName struct {
 		Id          int
@@ -192,7 +263,7 @@ func BenchmarkSQLGenerator_PrepareInsert(b *testing.B) {
 		Name:        "thisisname",
 		CreatedTime: time.Now(),
 	}
-	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper))
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -200,3 +271,245 @@ func BenchmarkSQLGenerator_PrepareInsert(b *testing.B) {
 		}
 	})
 }
+
+func TestSQLGenerator_SetInsertID(t *testing.T) {
+	type IntID struct {
+		ID   int8
+		Name string
+	}
+	type StringID struct {
+		ID   string
+		Name string
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+
+	i := &IntID{}
+	if err := sg.SetInsertID(i, 12); err != nil || i.ID != 12 {
+		t.Fatal(err, i.ID)
+	}
+	if err := sg.SetInsertID(i, 1024); err == nil {
+		t.Fatal("overflow must be error")
+	}
+
+	s := &StringID{}
+	if err := sg.SetInsertID(s, 12); err != nil || s.ID != "12" {
+		t.Fatal(err, s.ID)
+	}
+	if err := sg.SetInsertID(StringID{}, 12); err != ErrNotAddressable {
+		t.Fatal(err)
+	}
+
+	// 已经给出字符串ID时需要插入ID
+	sql, _, key, _ := sg.PrepareInsertObject(s)
+	if sql != "INSERT INTO `string_id` (`id`,`name`) VALUES (?,?)" || key != KeyGiven {
+		t.Fatal(sql, key)
+	}
+}
+
+func TestSQLGenerator_PrimaryKey(t *testing.T) {
+	type TaggedKey struct {
+		UserID int64 `db:"user_id,pk,autoincr"`
+		Name   string
+	}
+	type CompositeKey struct {
+		Name     string
+		TenantID int64  `db:"tenant_id,pk"`
+		UUID     string `db:"uuid,pk"`
+	}
+	type NoKey struct {
+		Name string
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+
+	tk := &TaggedKey{UserID: 1, Name: "a"}
+	sql, args, err := sg.PrepareUpdateByID(tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "UPDATE `tagged_key` SET `name`=? WHERE `user_id` = ?" || !reflect.DeepEqual(args, []interface{}{"a", int64(1)}) {
+		t.Fatal(sql, args)
+	}
+	tk.UserID = 0
+	sql, _, key, _ := sg.PrepareInsertObject(tk)
+	if sql != "INSERT INTO `tagged_key` (`name`) VALUES (?)" || key != KeyLastInsertID {
+		t.Fatal(sql, key)
+	}
+
+	ck := &CompositeKey{Name: "a", TenantID: 2, UUID: "u"}
+	sql, args, err = sg.PrepareUpdateByID(ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "UPDATE `composite_key` SET `name`=? WHERE `tenant_id` = ? AND `uuid` = ?" || !reflect.DeepEqual(args, []interface{}{"a", int64(2), "u"}) {
+		t.Fatal(sql, args)
+	}
+	// 非自增主键总是需要插入
+	sql, _, key, _ = sg.PrepareInsertObject(ck)
+	if sql != "INSERT INTO `composite_key` (`name`,`uuid`,`tenant_id`) VALUES (?,?,?)" || key != KeyGiven {
+		t.Fatal(sql, key)
+	}
+
+	nk := &NoKey{Name: "a"}
+	if _, _, err := sg.PrepareUpdateByID(nk); err != ErrNoPrimaryKey {
+		t.Fatal(err)
+	}
+	sql, _, key, err = sg.PrepareInsertObject(nk)
+	if err != nil || sql != "INSERT INTO `no_key` (`name`) VALUES (?)" || key != KeyGiven {
+		t.Fatal(sql, key, err)
+	}
+}
+
+func TestSQLGenerator_PrepareDeleteAndSelectByID(t *testing.T) {
+	type CompositeKey struct {
+		Name     string
+		TenantID int64  `db:"tenant_id,pk"`
+		UUID     string `db:"uuid,pk"`
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), Postgres)
+
+	sql, args, err := sg.PrepareDeleteByID(&CompositeKey{TenantID: 1, UUID: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != `DELETE FROM "composite_key" WHERE "tenant_id" = $1 AND "uuid" = $2` || !reflect.DeepEqual(args, []interface{}{int64(1), "u"}) {
+		t.Fatal(sql, args)
+	}
+
+	sql, _ = sg.PrepareDelete(CompositeKey{})
+	if sql != `DELETE FROM "composite_key"` {
+		t.Fatal(sql)
+	}
+
+	sql, args, err = sg.PrepareSelectByID(&CompositeKey{}, []interface{}{1, "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != `SELECT "name","uuid","tenant_id" FROM "composite_key" WHERE "tenant_id" = $1 AND "uuid" = $2` {
+		t.Fatal(sql)
+	}
+	if _, _, err = sg.PrepareSelectByID(&CompositeKey{}, []interface{}{1}); err == nil {
+		t.Fatal("key count mismatch must be error")
+	}
+	if _, _, err = sg.PrepareSelectByID(&[]CompositeKey{}, []interface{}{1, "u"}); !errors.Is(err, ErrInvalidScanType) {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLGenerator_PrepareUpdatePartial(t *testing.T) {
+	type PartialUser struct {
+		ID       int64
+		Name     string
+		Status   int
+		Nickname *string
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+
+	u := &PartialUser{ID: 1, Name: "a", Status: 2}
+	sql, args, err := sg.PrepareUpdateFields(u, []string{"status", "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "UPDATE `partial_user` SET `status`=?,`name`=? WHERE `id` = ?" || !reflect.DeepEqual(args, []interface{}{2, "a", int64(1)}) {
+		t.Fatal(sql, args)
+	}
+	if _, _, err = sg.PrepareUpdateFields(u, []string{"id"}); err == nil {
+		t.Fatal("primary key is not updatable")
+	}
+
+	sql, args, _ = sg.PrepareUpdateNonZero(&PartialUser{ID: 1, Status: 3})
+	if sql != "UPDATE `partial_user` SET `status`=? WHERE `id` = ?" || !reflect.DeepEqual(args, []interface{}{3, int64(1)}) {
+		t.Fatal(sql, args)
+	}
+	// 指针不为nil时即使指向零值也需要更新
+	empty := ""
+	sql, args, _ = sg.PrepareUpdateNonZero(&PartialUser{ID: 1, Nickname: &empty})
+	if sql != "UPDATE `partial_user` SET `nickname`=? WHERE `id` = ?" || args[0] != &empty {
+		t.Fatal(sql, args)
+	}
+	sql, _, err = sg.PrepareUpdateNonZero(&PartialUser{ID: 1})
+	if sql != "" || err != nil {
+		t.Fatal(sql, err)
+	}
+}
+
+// 部分更新时主键与自增列按完整的列推断 不能因为pk列不在更新的列中就把名为id的普通列当作主键
+func TestSQLGenerator_PrepareUpdatePartialWithPK(t *testing.T) {
+	type PKUser struct {
+		UserID int64  `db:"user_id,pk"`
+		ID     int64  `db:"id"`
+		Name   string `db:"name,omitempty"`
+	}
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	u := &PKUser{UserID: 1, ID: 2}
+
+	sql, args, err := sg.PrepareUpdateFields(u, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "UPDATE `pkuser` SET `id`=? WHERE `user_id` = ?" || !reflect.DeepEqual(args, []interface{}{int64(2), int64(1)}) {
+		t.Fatal(sql, args)
+	}
+	sql, args, _ = sg.PrepareUpdateNonZero(u)
+	if sql != "UPDATE `pkuser` SET `id`=? WHERE `user_id` = ?" || !reflect.DeepEqual(args, []interface{}{int64(2), int64(1)}) {
+		t.Fatal(sql, args)
+	}
+	// omitempty去掉name之后只剩id
+	sql, args, _ = sg.PrepareUpdateByID(u)
+	if sql != "UPDATE `pkuser` SET `id`=? WHERE `user_id` = ?" || !reflect.DeepEqual(args, []interface{}{int64(2), int64(1)}) {
+		t.Fatal(sql, args)
+	}
+	sql, args, _ = sg.PrepareUpdate(u)
+	if sql != "UPDATE `pkuser` SET `id`=?" || !reflect.DeepEqual(args, []interface{}{int64(2)}) {
+		t.Fatal(sql, args)
+	}
+}
+
+func TestSQLGenerator_TagOptions(t *testing.T) {
+	type TaggedUser struct {
+		ID        int64
+		Name      string
+		Secret    string    `db:"-"`
+		Score     int       `db:"score,readonly"`
+		CreatedAt time.Time `db:"created_at,insertonly"`
+		Nick      string    `db:"nick,omitempty"`
+		Status    int       `db:"status,default"`
+	}
+	fields := Fields(reflect.TypeOf(TaggedUser{}), SnakeMapper)
+	if _, ok := fields["-"]; ok {
+		t.Fatal("ignored field must not be mapped")
+	}
+	if _, ok := fields["secret"]; ok {
+		t.Fatal("ignored field must not be mapped")
+	}
+
+	sg := NewSQLGenerator(NewTypeFieldProducer(SnakeMapper), MySQL)
+	now := time.Now()
+	u := &TaggedUser{Name: "a", CreatedAt: now, Nick: "n", Status: 2}
+	sql, _, _, _ := sg.PrepareInsertObject(u)
+	if sql != "INSERT INTO `tagged_user` (`name`,`nick`,`status`,`created_at`) VALUES (?,?,?,?)" {
+		t.Fatal(sql)
+	}
+	// 零值的 omitempty 与 default 列不插入
+	u = &TaggedUser{Name: "a", CreatedAt: now}
+	sql, args, _, _ := sg.PrepareInsertObject(u)
+	if sql != "INSERT INTO `tagged_user` (`name`,`created_at`) VALUES (?,?)" || !reflect.DeepEqual(args, []interface{}{"a", now}) {
+		t.Fatal(sql, args)
+	}
+
+	u = &TaggedUser{ID: 1, Name: "a", Nick: "n"}
+	sql, _, _ = sg.PrepareUpdateByID(u)
+	if sql != "UPDATE `tagged_user` SET `name`=?,`nick`=?,`status`=? WHERE `id` = ?" {
+		t.Fatal(sql)
+	}
+	// 零值的 omitempty 列不更新 default 只影响插入
+	u.Nick = ""
+	sql, args, _ = sg.PrepareUpdateByID(u)
+	if sql != "UPDATE `tagged_user` SET `name`=?,`status`=? WHERE `id` = ?" || !reflect.DeepEqual(args, []interface{}{"a", 0, int64(1)}) {
+		t.Fatal(sql, args)
+	}
+
+	sql, _ = sg.PrepareSelectFrom(u)
+	if sql != "SELECT `id`,`name`,`nick`,`score`,`status`,`created_at` FROM `tagged_user` " {
+		t.Fatal(sql)
+	}
+}
@@ -0,0 +1,84 @@
+package internel
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Strictness 扫描到结构体时 对结果列与属性不匹配的检查
+type Strictness int
+
+const (
+	StrictNone           Strictness = 0                                          // 不检查 无法对应属性的列被忽略
+	StrictUnknownColumns Strictness = 1                                          // 结果中有无法对应属性的列时返回错误
+	StrictMissingFields  Strictness = 2                                          // 结构体中有属性未出现在结果中时返回错误
+	StrictAll                       = StrictUnknownColumns | StrictMissingFields // 同时检查两者
+)
+
+// StrictScanError 严格模式下结果列与结构体属性不匹配
+type StrictScanError struct {
+	Type           reflect.Type
+	UnknownColumns []string // 无法对应属性的列
+	MissingFields  []string // 未出现在结果中的属性 格式为 属性名(列名)
+}
+
+func (e *StrictScanError) Error() string {
+	var parts []string
+	if len(e.UnknownColumns) > 0 {
+		parts = append(parts, "unknown columns ["+strings.Join(e.UnknownColumns, ", ")+"]")
+	}
+	if len(e.MissingFields) > 0 {
+		parts = append(parts, "missing fields ["+strings.Join(e.MissingFields, ", ")+"]")
+	}
+	return fmt.Sprintf("strict scan into %s: %s", e.Type, strings.Join(parts, "; "))
+}
+
+// checkStrict 检查结果列与结构体属性是否匹配 不匹配时返回 *StrictScanError
+func (builder *ValuesProducerBuilder) checkStrict(info *TypeInfo, columnNames []string, strict Strictness) error {
+	switch info.Type {
+	case TypeStruct, TypeSliceOfStruct, TypeSliceOfPtrToStruct, TypeMapOfStruct, TypeMapOfPtrToStruct, TypeMapOfSliceOfStruct:
+	default:
+		return nil
+	}
+	fields := builder.fieldProducer.Fields(info.ElemType)
+	e := &StrictScanError{Type: info.ElemType}
+	if strict&StrictUnknownColumns != 0 {
+		for _, col := range columnNames {
+			if _, ok := fields[col]; !ok {
+				e.UnknownColumns = append(e.UnknownColumns, col)
+			}
+		}
+	}
+	if strict&StrictMissingFields != 0 {
+		present := make(map[string]bool, len(columnNames))
+		for _, col := range columnNames {
+			present[col] = true
+		}
+		for _, field := range toNamedFields(fields) {
+			if !present[field.Name] {
+				e.MissingFields = append(e.MissingFields, fieldName(info.ElemType, field.index())+"("+field.Name+")")
+			}
+		}
+		sort.Strings(e.MissingFields)
+	}
+	if len(e.UnknownColumns) == 0 && len(e.MissingFields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// fieldName 按索引路径获取属性名 嵌入的属性以 . 连接
+func fieldName(typ reflect.Type, index []int) string {
+	names := make([]string, 0, len(index))
+	for _, i := range index {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		f := typ.Field(i)
+		names = append(names, f.Name)
+		typ = f.Type
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,53 @@
+package internel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+type testStrictInner struct {
+	Extra string `db:"extra"`
+}
+
+type testStrictStruct struct {
+	A string `db:"a"`
+	B string `db:"b"`
+	testStrictInner
+}
+
+func TestRowsScanner_Strict(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	scan := func(scanner *RowsScanner, ptr interface{}) error {
+		mock.ExpectQuery("query").WillReturnRows(sqlmock.NewRows([]string{"a", "c"}).AddRow("1", "2"))
+		rows, _ := db.QueryContext(context.Background(), "query")
+		return scanner.Scan(rows, ptr)
+	}
+	var row testStrictStruct
+	if err := scan(GlobalScanner, &row); err != nil || row.A != "1" {
+		t.Fatal(row, err)
+	}
+
+	var strictErr *StrictScanError
+	err := scan(GlobalScanner.WithStrictness(StrictUnknownColumns), &row)
+	if !errors.As(err, &strictErr) || len(strictErr.UnknownColumns) != 1 || strictErr.UnknownColumns[0] != "c" || strictErr.MissingFields != nil {
+		t.Fatal(err)
+	}
+
+	err = scan(GlobalScanner.WithStrictness(StrictAll), &[]testStrictStruct{})
+	if !errors.As(err, &strictErr) || len(strictErr.MissingFields) != 2 ||
+		strictErr.MissingFields[0] != "B(b)" || strictErr.MissingFields[1] != "testStrictInner.Extra(extra)" {
+		t.Fatal(err)
+	}
+	if err.Error() != "strict scan into internel.testStrictStruct: unknown columns [c]; missing fields [B(b), testStrictInner.Extra(extra)]" {
+		t.Fatal(err)
+	}
+
+	// 非结构体不检查
+	var a string
+	if err = scan(GlobalScanner.WithStrictness(StrictAll), &a); err != nil || a != "1" {
+		t.Fatal(a, err)
+	}
+}
@@ -16,6 +16,11 @@ const (
 	TypeSliceOfRawType
 	TypeSliceOfPtrToStruct
 	TypeSliceOfPtrToRawType
+	TypeMap                // map[string]interface{} 以列名为键
+	TypeSliceOfMap         // []map[string]interface{}
+	TypeMapOfStruct        // map[K]T 按键列的值写入
+	TypeMapOfPtrToStruct   // map[K]*T
+	TypeMapOfSliceOfStruct // map[K][]T 键相同的行按顺序追加
 )
 
 func (typ SupportType) String() string {
@@ -32,6 +37,16 @@ func (typ SupportType) String() string {
 		return "TypeSliceOfPtrToStruct"
 	case TypeSliceOfPtrToRawType:
 		return "TypeSliceOfPtrToRawType"
+	case TypeMap:
+		return "TypeMap"
+	case TypeSliceOfMap:
+		return "TypeSliceOfMap"
+	case TypeMapOfStruct:
+		return "TypeMapOfStruct"
+	case TypeMapOfPtrToStruct:
+		return "TypeMapOfPtrToStruct"
+	case TypeMapOfSliceOfStruct:
+		return "TypeMapOfSliceOfStruct"
 	default:
 		return ""
 	}
@@ -47,18 +62,29 @@ func (typ SupportType) RowProducer(value reflect.Value) (p RowProducer) {
 		p = sliceOfPtrRP(value)
 	case TypeSliceOfStruct, TypeSliceOfRawType:
 		p = sliceRP(value)
+	case TypeMap:
+		p = mapRP(value)
+	case TypeSliceOfMap:
+		p = sliceOfMapRP(value)
 	}
 	return p
 }
 
+// Keyed 是否为按键列写入的map
+func (typ SupportType) Keyed() bool {
+	return typ == TypeMapOfStruct || typ == TypeMapOfPtrToStruct || typ == TypeMapOfSliceOfStruct
+}
+
 type TypeInfo struct {
 	Type     SupportType
 	ElemType reflect.Type
+	KeyType  reflect.Type // 按键列写入的map的键类型
 }
 
 var (
 	bytesType = reflect.TypeOf([]byte{})
 	timeType  = reflect.TypeOf(time.Time{})
+	mapType   = reflect.TypeOf(map[string]interface{}{})
 )
 
 func NewTypeInfoFactory() *TypeInfoFactory {
@@ -74,8 +100,8 @@ type TypeInfoFactory struct {
 
 func (f *TypeInfoFactory) Get(obj interface{}) (info *TypeInfo, err error) {
 	typ := reflect.TypeOf(obj)
-	if typ.Kind() != reflect.Ptr {
-		return nil, ErrInvalidScanType
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return nil, &ScanTypeError{Type: typ}
 	}
 
 	f.mutex.RLock()
@@ -87,7 +113,7 @@ func (f *TypeInfoFactory) Get(obj interface{}) (info *TypeInfo, err error) {
 
 	info, _ = getInfo(typ)
 	if info == nil {
-		return nil, ErrInvalidScanType
+		return nil, &ScanTypeError{Type: typ}
 	}
 	f.mutex.Lock()
 	f.cache[typ] = info
@@ -108,6 +134,10 @@ func getInfo(typ reflect.Type) (info *TypeInfo, pointer bool) {
 		info.Type = TypeRawType
 		info.ElemType = typ
 		return
+	case mapType:
+		info.Type = TypeMap
+		info.ElemType = typ
+		return
 	}
 	// 类型断言是否为scanner
 	if _, ok := typ.(sql.Scanner); ok {
@@ -135,11 +165,38 @@ func getInfo(typ reflect.Type) (info *TypeInfo, pointer bool) {
 			} else {
 				info.Type = TypeSliceOfStruct
 			}
+		case TypeMap:
+			if isPointer {
+				return nil, false
+			}
+			info.Type = TypeSliceOfMap
 		default:
 			return nil, false
 		}
 		info.ElemType = subInfo.ElemType
 
+	case reflect.Map:
+		switch typ.Key().Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.String:
+		default:
+			return nil, false
+		}
+		subInfo, isPointer := getInfo(typ.Elem())
+		if subInfo == nil {
+			return nil, false
+		}
+		switch {
+		case subInfo.Type == TypeStruct && isPointer:
+			info.Type = TypeMapOfPtrToStruct
+		case subInfo.Type == TypeStruct:
+			info.Type = TypeMapOfStruct
+		case subInfo.Type == TypeSliceOfStruct:
+			info.Type = TypeMapOfSliceOfStruct
+		default:
+			return nil, false
+		}
+		info.ElemType = subInfo.ElemType
+		info.KeyType = typ.Key()
 	case reflect.Struct:
 		info.Type = TypeStruct
 		info.ElemType = typ
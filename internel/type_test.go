@@ -24,11 +24,20 @@ var testTypeInfoFactoryTable = []struct {
 	{&[]*testTypeInfoFactoryStruct{}, TypeSliceOfPtrToStruct, reflect.TypeOf(testTypeInfoFactoryStruct{})},
 	{&[]*string{}, TypeSliceOfPtrToRawType, reflect.TypeOf("")},
 	{&[]*[]byte{}, TypeSliceOfPtrToRawType, reflect.TypeOf([]byte{})},
+	{&map[string]interface{}{}, TypeMap, reflect.TypeOf(map[string]interface{}{})},
+	{&[]map[string]interface{}{}, TypeSliceOfMap, reflect.TypeOf(map[string]interface{}{})},
+	{&map[int64]testTypeInfoFactoryStruct{}, TypeMapOfStruct, reflect.TypeOf(testTypeInfoFactoryStruct{})},
+	{&map[string]*testTypeInfoFactoryStruct{}, TypeMapOfPtrToStruct, reflect.TypeOf(testTypeInfoFactoryStruct{})},
+	{&map[string][]testTypeInfoFactoryStruct{}, TypeMapOfSliceOfStruct, reflect.TypeOf(testTypeInfoFactoryStruct{})},
 }
 
 var testTypeInfoFactoryErrorTable = []interface{}{
 	&[]*[][]byte{},
 	&ptrTotestTypeInfoFactoryStr,
+	&map[string]string{},
+	&[]*map[string]interface{}{},
+	&map[string][]*testTypeInfoFactoryStruct{},
+	&map[interface{}]testTypeInfoFactoryStruct{},
 }
 
 func TestTypeInfoFactory_Get(t *testing.T) {
@@ -1,6 +1,7 @@
 package internel
 
 import (
+	"database/sql"
 	"reflect"
 )
 
@@ -14,31 +15,102 @@ func NewValuesProducerBuilder(fieldProducer *TypeFieldProducer) *ValuesProducerB
 type ValuesProducerBuilder struct {
 	fieldProducer   *TypeFieldProducer
 	typeInfoFactory *TypeInfoFactory
+	generation      uint32 // 每次修改Mapper时加一 使之前创建的ScanPlan失效
 }
 
 func (builder *ValuesProducerBuilder) Build(obj interface{}, columnNames []string) (ValuesProducer, bool, error) {
-	info, err := builder.typeInfoFactory.Get(obj)
-	if err != nil {
+	return builder.build(obj, columnNames, nil, StrictNone)
+}
+
+// BuildRows 与Build相同 扫描到map时使用rows的列类型转换值
+func (builder *ValuesProducerBuilder) BuildRows(obj interface{}, rows SQLRows, columnNames []string) (ValuesProducer, bool, error) {
+	return builder.build(obj, columnNames, rows, StrictNone)
+}
+
+func (builder *ValuesProducerBuilder) build(obj interface{}, columnNames []string, rows SQLRows, strict Strictness) (ValuesProducer, bool, error) {
+	keyColumnName := ""
+	if keyed, ok := obj.(*KeyedBy); ok {
+		obj, keyColumnName = keyed.Ptr, keyed.Column
+	}
+	var plan *ScanPlan
+	if planned, ok := obj.(*Planned); ok {
+		obj = planned.Ptr
+		if planned.Plan.usableBy(builder, obj) {
+			plan = planned.Plan
+		}
+	}
+	var info *TypeInfo
+	var err error
+	if plan != nil {
+		info = plan.info
+	} else if info, err = builder.typeInfoFactory.Get(obj); err != nil {
 		return nil, false, err
 	}
 
+	if strict != StrictNone {
+		if err = builder.checkStrict(info, columnNames, strict); err != nil {
+			return nil, false, err
+		}
+	}
+
 	value := reflect.ValueOf(obj)
 
 	rowProducer := info.Type.RowProducer(value)
 
-	columns := builder.GetColumns(info, columnNames)
+	var columns []Column
+	if info.Type == TypeMap || info.Type == TypeSliceOfMap {
+		var types []*sql.ColumnType
+		if rows != nil {
+			types = columnTypes(rows)
+		}
+		columns = builder.mapColumns(columnNames, types)
+	} else if plan != nil {
+		columns = plan.columns(columnNames)
+	} else {
+		columns = builder.GetColumns(info, columnNames)
+	}
+
+	var flush func()
+	if info.Type.Keyed() {
+		index, err := builder.keyIndex(info, columnNames, keyColumnName)
+		if err != nil {
+			return nil, false, err
+		}
+		key := reflect.New(info.KeyType)
+		rowProducer, flush = keyedMapRP(info.Type, value, key)
+		// ScanPlan中的columns为共享的 需要复制后再替换键列
+		columns = append([]Column(nil), columns...)
+		columns[index] = &keyColumn{Column: columns[index], key: key}
+	} else if keyColumnName != "" {
+		return nil, false, &ScanTypeError{Type: value.Type()}
+	}
 
 	return &valuesProducer{
 		columns:     columns,
 		rowProducer: rowProducer,
 		cache:       make([]interface{}, len(columns)),
-	}, info.Type == TypeRawType || info.Type == TypeStruct, nil
+		flush:       flush,
+		elemType:    info.ElemType,
+	}, info.Type == TypeRawType || info.Type == TypeStruct || info.Type == TypeMap, nil
+}
+
+// mapColumns 扫描到map时每列对应一个以列名为键的MapColumn types为nil或长度不符时不转换类型
+func (builder *ValuesProducerBuilder) mapColumns(columnNames []string, types []*sql.ColumnType) []Column {
+	columns := make([]Column, 0, len(columnNames))
+	for i, name := range columnNames {
+		var ct *sql.ColumnType
+		if len(types) == len(columnNames) {
+			ct = types[i]
+		}
+		columns = append(columns, NewMapColumn(name, ct))
+	}
+	return columns
 }
 
 func (builder *ValuesProducerBuilder) GetColumns(info *TypeInfo, columnNames []string) (columns []Column) {
 	columns = make([]Column, 0, len(columnNames))
 	switch info.Type {
-	case TypeStruct, TypeSliceOfPtrToStruct, TypeSliceOfStruct:
+	case TypeStruct, TypeSliceOfPtrToStruct, TypeSliceOfStruct, TypeMapOfStruct, TypeMapOfPtrToStruct, TypeMapOfSliceOfStruct:
 		// struct columns
 		fields := builder.fieldProducer.Fields(info.ElemType)
 		for _, col := range columnNames {
@@ -1,5 +1,10 @@
 package internel
 
+import (
+	"fmt"
+	"reflect"
+)
+
 // ValuesProducer 多列提供者
 // 用于提供每一行扫描时所需的多列的实体
 // 如
@@ -12,10 +17,23 @@ type ValuesProducer interface {
 	Values() []interface{}
 }
 
+// Flusher 扫描完所有行后需要调用Flush的ValuesProducer 如按键写入map时 最后一行在Flush时写入
+type Flusher interface {
+	Flush()
+}
+
 type valuesProducer struct {
 	columns     []Column
 	rowProducer RowProducer
 	cache       []interface{}
+	flush       func()
+	elemType    reflect.Type // 每一行的类型 用于在错误信息中描述列的目标
+}
+
+func (s *valuesProducer) Flush() {
+	if s.flush != nil {
+		s.flush()
+	}
 }
 
 func (s *valuesProducer) Values() []interface{} {
@@ -26,3 +44,20 @@ func (s *valuesProducer) Values() []interface{} {
 	}
 	return s.cache
 }
+
+// describe 描述第i列写入的目标
+func (s *valuesProducer) describe(i int) string {
+	column := s.columns[i]
+	if key, ok := column.(*keyColumn); ok {
+		column = key.Column
+	}
+	switch c := column.(type) {
+	case *Field:
+		return s.elemType.String() + "." + fieldName(s.elemType, c.index())
+	case *MapColumn:
+		return fmt.Sprintf("%s[%q]", s.elemType, c.Name)
+	case IgnoredColumn:
+		return "ignored column"
+	}
+	return s.elemType.String()
+}
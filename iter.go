@@ -0,0 +1,45 @@
+//go:build go1.23
+
+package sqlhelper
+
+import (
+	"context"
+	"iter"
+)
+
+// QueryIter 返回逐行遍历查询结果的迭代器 T为结构体或基础类型
+// 如
+//
+//	for row, err := range sqlhelper.QueryIter[User](ctx, helper, "SELECT * FROM user") {
+//	    if err != nil {
+//	        return err
+//	    }
+//	}
+//
+// 所有行共用同一个*T 每行扫描前重置为零值 循环体不应持有row 提前退出循环时关闭游标
+// 出现错误时以 (nil, err) 结束遍历
+func QueryIter[T any](ctx context.Context, helper SQLHelper, sqlstr string, args ...interface{}) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		cursor, err := helper.QueryCursor(ctx, sqlstr, args...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cursor.Close()
+		row := new(T)
+		var zero T
+		for cursor.Next() {
+			*row = zero
+			if err := cursor.Scan(row); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
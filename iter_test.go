@@ -0,0 +1,32 @@
+//go:build go1.23
+
+package sqlhelper
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestQueryIter(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b").AddRow(3, "c"))
+	var names []string
+	for row, err := range QueryIter[testUser](context.Background(), helper, "SELECT id, name FROM test_user") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, row.Name)
+		if row.ID == 2 {
+			break
+		}
+	}
+	if len(names) != 2 || names[1] != "b" {
+		t.Fatal(names)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package sqlhelper
+
+import (
+	"database/sql"
+
+	"github.com/cocotyty/sqlhelper/internel"
+)
+
+// Option 创建SQLHelper时的可选配置
+type Option func(s *sqlHelper)
+
+// WithDialect 使用指定的数据库方言生成SQL 默认为 internel.MySQL
+// 创建时复制当前生成器中通过MapTable关联的表 之后的关联需要在WithSQLGenerator传入的生成器上进行
+func WithDialect(dialect internel.Dialect) Option {
+	return func(s *sqlHelper) {
+		s.SQLGenerator = s.SQLGenerator.ForkDialect(dialect)
+	}
+}
+
+// WithSQLGenerator 使用指定的生成器 调用方可以继续在该生成器上关联表
+func WithSQLGenerator(generator *internel.SQLGenerator) Option {
+	return func(s *sqlHelper) {
+		s.SQLGenerator = generator
+	}
+}
+
+// WithMaxPlaceholders 设置批量插入时单条语句最多使用的参数个数 超出时拆分为多条语句 默认使用方言的上限
+// 复制当前的生成器后设置 不影响全局生成器与其他SQLHelper
+func WithMaxPlaceholders(n int) Option {
+	return func(s *sqlHelper) {
+		s.SQLGenerator = s.SQLGenerator.Fork()
+		s.SQLGenerator.SetMaxPlaceholders(n)
+	}
+}
+
+// WithSliceExpansion 开启slice参数展开 QueryContext SelectFrom UpdateContext DeleteContext 等方法中
+// slice参数([]byte与driver.Valuer除外)会被展开为对应个数的占位符 如 id IN (?) 传入 []int{1,2,3}
+// 展开为 id IN (?,?,?) Postgres 等方言的 $n 占位符会被重新编号
+func WithSliceExpansion() Option {
+	return func(s *sqlHelper) {
+		s.expandSlices = true
+	}
+}
+
+// WithStrictScan 扫描到结构体时检查结果列与属性是否匹配 不匹配时返回 *internel.StrictScanError
+// 如 internel.StrictUnknownColumns 在结果中有无法对应属性的列时返回错误
+func WithStrictScan(strict internel.Strictness) Option {
+	return func(s *sqlHelper) {
+		s.Scanner = s.Scanner.WithStrictness(strict)
+	}
+}
+
+// WithStmtCache 使用cache缓存完全由生成器生成的CRUD语句的预编译语句 如 InsertObject UpdateObjectByID DeleteObjectByID GetByID
+// 手写的SQL与包含手写部分的SelectFrom不会被缓存 cache必须由创建SQLHelper时使用的 *sql.DB 创建 否则忽略
+func WithStmtCache(cache *StmtCache) Option {
+	return func(s *sqlHelper) {
+		if db, ok := s.db.(*sql.DB); ok && cache != nil && cache.db == db {
+			s.stmts = cache
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// Condition 查询条件 使用 ? 作为占位符 执行前会转换为方言的占位符
+type Condition interface {
+	Build() (sql string, args []interface{})
+}
+
+type exprCondition struct {
+	sql  string
+	args []interface{}
+}
+
+func (c exprCondition) Build() (string, []interface{}) {
+	return c.sql, c.args
+}
+
+// Expr 由SQL片段与参数构成的条件 如 Expr("age > ?", 18)
+func Expr(sql string, args ...interface{}) Condition {
+	return exprCondition{sql: sql, args: args}
+}
+
+type inCondition struct {
+	column string
+	values interface{}
+}
+
+// In column IN (...) 条件 values为slice或数组 为空时条件恒为假
+func In(column string, values interface{}) Condition {
+	return inCondition{column: column, values: values}
+}
+
+func (c inCondition) Build() (string, []interface{}) {
+	val := reflect.ValueOf(c.values)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return c.column + " IN (?)", []interface{}{c.values}
+	}
+	if val.Len() == 0 {
+		return "1 = 0", nil
+	}
+	args := make([]interface{}, val.Len())
+	for i := range args {
+		args[i] = val.Index(i).Interface()
+	}
+	return c.column + " IN (?" + strings.Repeat(",?", len(args)-1) + ")", args
+}
+
+// QueryBuilder 链式构造的查询 通过SQLHelper.From创建
+// 列与表由ptr的类型决定 与SelectFrom相同
+type QueryBuilder struct {
+	helper  *sqlHelper
+	ptr     interface{}
+	where   string
+	lastOp  string
+	args    []interface{}
+	orderBy []string
+	limit   int64
+	offset  int64
+}
+
+// From 以ptr为查询结果创建链式查询 ptr支持的类型与SelectFrom相同
+func (s *sqlHelper) From(ptr interface{}) *QueryBuilder {
+	return &QueryBuilder{
+		helper: s,
+		ptr:    ptr,
+		limit:  -1,
+	}
+}
+
+// Where 以AND连接条件 如 Where("age > ?", 18)
+func (q *QueryBuilder) Where(sql string, args ...interface{}) *QueryBuilder {
+	return q.And(Expr(sql, args...))
+}
+
+// And 以AND连接条件
+func (q *QueryBuilder) And(cond Condition) *QueryBuilder {
+	return q.join("AND", cond)
+}
+
+// Or 以OR连接条件
+func (q *QueryBuilder) Or(cond Condition) *QueryBuilder {
+	return q.join("OR", cond)
+}
+
+// join 按调用顺序从左到右结合条件 a AND b OR c AND d 等价于 ((a AND b) OR c) AND d
+func (q *QueryBuilder) join(op string, cond Condition) *QueryBuilder {
+	sql, args := cond.Build()
+	switch {
+	case q.where == "":
+		q.where = "(" + sql + ")"
+	case q.lastOp != "" && q.lastOp != op:
+		q.where = "(" + q.where + ") " + op + " (" + sql + ")"
+		q.lastOp = op
+	default:
+		q.where += " " + op + " (" + sql + ")"
+		q.lastOp = op
+	}
+	q.args = append(q.args, args...)
+	return q
+}
+
+// OrderBy 排序 如 OrderBy("id DESC")
+func (q *QueryBuilder) OrderBy(orders ...string) *QueryBuilder {
+	q.orderBy = append(q.orderBy, orders...)
+	return q
+}
+
+// Limit 最多返回n行
+func (q *QueryBuilder) Limit(n int64) *QueryBuilder {
+	q.limit = n
+	return q
+}
+
+// Offset 跳过前n行
+func (q *QueryBuilder) Offset(n int64) *QueryBuilder {
+	q.offset = n
+	return q
+}
+
+// build 生成以selectSQL开头的完整语句
+func (q *QueryBuilder) build(selectSQL string, paging bool) string {
+	buf := strings.Builder{}
+	buf.WriteString(strings.TrimSpace(selectSQL))
+	if q.where != "" {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(q.where)
+	}
+	if paging {
+		if len(q.orderBy) > 0 {
+			buf.WriteString(" ORDER BY ")
+			buf.WriteString(strings.Join(q.orderBy, ","))
+		}
+		if page := q.helper.SQLGenerator.Dialect().LimitOffset(q.limit, q.offset, len(q.orderBy) > 0); page != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(page)
+		}
+	}
+	return q.helper.SQLGenerator.Rebind(buf.String())
+}
+
+// Find 执行查询并将结果写入ptr 单行类型未查询到时返回sql.ErrNoRows
+func (q *QueryBuilder) Find(ctx context.Context) error {
+	selectSQL, err := q.helper.SQLGenerator.PrepareSelectFrom(q.ptr)
+	if err != nil {
+		return err
+	}
+	return q.helper.QueryContext(ctx, q.ptr, q.build(selectSQL, true), q.args...)
+}
+
+// First 只查询第一行 不修改当前查询的Limit
+func (q *QueryBuilder) First(ctx context.Context) error {
+	first := *q
+	first.limit = 1
+	return first.Find(ctx)
+}
+
+// Count 统计满足条件的行数 忽略排序与分页
+func (q *QueryBuilder) Count(ctx context.Context) (count int64, err error) {
+	selectSQL, err := q.helper.SQLGenerator.PrepareSelectExpr(q.ptr, "COUNT(*)")
+	if err != nil {
+		return 0, err
+	}
+	err = q.helper.QueryContext(ctx, &count, q.build(selectSQL, false), q.args...)
+	return
+}
+
+// Exists 是否存在满足条件的行
+func (q *QueryBuilder) Exists(ctx context.Context) (bool, error) {
+	selectSQL, err := q.helper.SQLGenerator.PrepareSelectExpr(q.ptr, "1")
+	if err != nil {
+		return false, err
+	}
+	exists := *q
+	exists.orderBy = nil
+	exists.limit, exists.offset = 1, 0
+	var one int
+	err = q.helper.QueryContext(ctx, &one, exists.build(selectSQL, true), q.args...)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,51 @@
+package sqlhelper
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/cocotyty/sqlhelper/internel"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	ctx := context.Background()
+	helper := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE (age > ?) AND (status IN (?,?)) ORDER BY id DESC LIMIT 20 OFFSET 40")).
+		WithArgs(18, 1, 2).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var users []testUser
+	err := helper.From(&users).Where("age > ?", 18).And(In("status", []int{1, 2})).OrderBy("id DESC").Limit(20).Offset(40).Find(ctx)
+	if err != nil || len(users) != 2 {
+		t.Fatal(err, users)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM `test_user` WHERE ((a = ?) AND (b = ?)) OR (c = ?)")).
+		WithArgs(1, 2, 3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	count, err := helper.From(&users).Where("a = ?", 1).Where("b = ?", 2).Or(Expr("c = ?", 3)).OrderBy("id").Limit(1).Count(ctx)
+	if err != nil || count != 7 {
+		t.Fatal(err, count)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM `test_user` WHERE (1 = 0) LIMIT 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	exists, err := helper.From(&users).And(In("id", []int{})).Exists(ctx)
+	if err != nil || exists {
+		t.Fatal(err, exists)
+	}
+
+	// Postgres 占位符与分页
+	helper = New(db, WithDialect(internel.Postgres))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","name" FROM "test_user" WHERE (name = $1) LIMIT 1`)).
+		WithArgs("a").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	u := &testUser{}
+	if err = helper.From(u).Where("name = ?", "a").First(ctx); err != nil || u.ID != 1 {
+		t.Fatal(err, u)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
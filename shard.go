@@ -0,0 +1,377 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+
+	"github.com/cocotyty/sqlhelper/internel"
+)
+
+// Shard 分片的位置
+type Shard struct {
+	DB     int    // 数据库在NewSharded的dbs中的下标
+	Suffix string // 表名后缀 如 _07 完整表名为类型关联的表名加后缀
+}
+
+// ShardRule 分片规则 根据分片键计算所在的分片
+type ShardRule interface {
+	// Locate 返回分片键key所在的分片
+	Locate(key interface{}) (Shard, error)
+	// Shards 返回所有的分片 用于跨分片查询
+	Shards() []Shard
+}
+
+// ModShard 按分片键取模分表 Tables张表按顺序平均分布到Databases个库中
+// 如 Tables为64 Databases为4时 _00.._15 位于第0个库 _16.._31 位于第1个库
+// 整数分片键直接取模 字符串分片键取 FNV-1a 哈希后取模
+type ModShard struct {
+	Tables    int
+	Databases int    // 为0时视为1
+	Format    string // 表名后缀的格式 为空时使用 _%02d
+}
+
+// Locate 实现ShardRule
+func (m ModShard) Locate(key interface{}) (Shard, error) {
+	if m.Tables <= 0 {
+		return Shard{}, fmt.Errorf("invalid shard tables: %d", m.Tables)
+	}
+	var n uint64
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = v.Uint()
+	case reflect.String:
+		h := fnv.New64a()
+		h.Write([]byte(v.String()))
+		n = h.Sum64()
+	default:
+		return Shard{}, fmt.Errorf("unsupported shard key type %T", key)
+	}
+	return m.shard(int(n % uint64(m.Tables))), nil
+}
+
+// Shards 实现ShardRule
+func (m ModShard) Shards() []Shard {
+	shards := make([]Shard, 0, m.Tables)
+	for i := 0; i < m.Tables; i++ {
+		shards = append(shards, m.shard(i))
+	}
+	return shards
+}
+
+func (m ModShard) shard(table int) Shard {
+	databases, format := m.Databases, m.Format
+	if databases <= 0 {
+		databases = 1
+	}
+	if format == "" {
+		format = "_%02d"
+	}
+	return Shard{DB: table * databases / m.Tables, Suffix: fmt.Sprintf(format, table)}
+}
+
+// ShardedHelper 分库分表的SQLHelper 通过Register为类型注册分片规则
+// 按对象操作的方法根据对象的分片键属性选择库与表 未注册的类型返回ErrNotSharded
+// 手写SQL与事务需要先通过Shard取得对应分片的SQLHelper
+type ShardedHelper struct {
+	shards     []*sqlHelper
+	locker     sync.RWMutex
+	tables     map[reflect.Type]*shardTable
+	generators map[shardGeneratorKey]*internel.SQLGenerator
+}
+
+// shardTable 类型注册的分片信息
+type shardTable struct {
+	name   string // 不带后缀的表名
+	column string // 分片键列
+	rule   ShardRule
+}
+
+type shardGeneratorKey struct {
+	typ    reflect.Type
+	suffix string
+}
+
+// NewSharded 创建分库分表的SQLHelper dbs按ShardRule中Shard.DB的下标排列 opts应用于每个库
+func NewSharded(dbs []*sql.DB, opts ...Option) *ShardedHelper {
+	h := &ShardedHelper{
+		tables:     map[reflect.Type]*shardTable{},
+		generators: map[shardGeneratorKey]*internel.SQLGenerator{},
+	}
+	for _, db := range dbs {
+		h.shards = append(h.shards, New(db, opts...).(*sqlHelper))
+	}
+	return h
+}
+
+// Register 为sample的类型注册分片规则 column为分片键的列名 表名为类型关联的表名加分片的后缀
+func (h *ShardedHelper) Register(sample interface{}, column string, rule ShardRule) error {
+	if len(h.shards) == 0 {
+		return fmt.Errorf("no database for sharding")
+	}
+	generator := h.shards[0].SQLGenerator
+	name, err := generator.TableName(sample)
+	if err != nil {
+		return err
+	}
+	typ := structType(sample)
+	if _, err = generator.ColumnValue(reflect.New(typ).Interface(), column); err != nil {
+		return err
+	}
+	for _, shard := range rule.Shards() {
+		if shard.DB < 0 || shard.DB >= len(h.shards) {
+			return fmt.Errorf("shard %s%s: database index %d out of range", name, shard.Suffix, shard.DB)
+		}
+	}
+	h.locker.Lock()
+	h.tables[typ] = &shardTable{name: name, column: column, rule: rule}
+	h.locker.Unlock()
+	return nil
+}
+
+// Shard 返回sample的类型中分片键为key的分片对应的SQLHelper
+// 其中按对象操作的方法使用分片的表名 可用于GetByID 手写SQL与事务
+func (h *ShardedHelper) Shard(sample interface{}, key interface{}) (SQLHelper, error) {
+	table, err := h.table(sample)
+	if err != nil {
+		return nil, err
+	}
+	shard, err := table.rule.Locate(key)
+	if err != nil {
+		return nil, err
+	}
+	return h.helper(structType(sample), table, shard)
+}
+
+// InsertObject 插入对象到分片键所在的分片 与SQLHelper.InsertObject相同
+func (h *ShardedHelper) InsertObject(ctx context.Context, object interface{}) (int64, error) {
+	helper, err := h.route(object)
+	if err != nil {
+		return 0, err
+	}
+	return helper.InsertObject(ctx, object)
+}
+
+// InsertObjects 按分片键将objects分组 每个分片分别批量插入 返回插入的总行数
+// 各分片按在objects中首次出现的顺序插入 出现错误时停止 已插入的分片不会回滚
+func (h *ShardedHelper) InsertObjects(ctx context.Context, objects interface{}, fillID bool) (total int64, err error) {
+	v := reflect.ValueOf(objects)
+	if v.Kind() != reflect.Slice {
+		return 0, &ScanTypeError{Type: reflect.TypeOf(objects)}
+	}
+	table, err := h.table(objects)
+	if err != nil {
+		return 0, err
+	}
+	typ := structType(objects)
+	var order []Shard
+	groups := map[Shard]reflect.Value{}
+	for i := 0; i < v.Len(); i++ {
+		// 使用元素的指针 保证自增主键写回到objects中
+		item := v.Index(i)
+		if item.Kind() == reflect.Struct {
+			item = item.Addr()
+		}
+		shard, err := h.locate(table, item.Interface())
+		if err != nil {
+			return 0, err
+		}
+		group, ok := groups[shard]
+		if !ok {
+			order = append(order, shard)
+			group = reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(typ)), 0, 1)
+		}
+		groups[shard] = reflect.Append(group, item)
+	}
+	for _, shard := range order {
+		helper, err := h.helper(typ, table, shard)
+		if err != nil {
+			return total, err
+		}
+		num, err := helper.InsertObjects(ctx, groups[shard].Interface(), fillID)
+		total += num
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// UpsertObject 在分片键所在的分片中插入或更新对象 与SQLHelper.UpsertObject相同
+func (h *ShardedHelper) UpsertObject(ctx context.Context, object interface{}, conflictColumns ...string) (int64, error) {
+	helper, err := h.route(object)
+	if err != nil {
+		return 0, err
+	}
+	return helper.UpsertObject(ctx, object, conflictColumns...)
+}
+
+// UpdateObjectByID 按主键更新分片键所在分片中的对象 分片键不可修改
+func (h *ShardedHelper) UpdateObjectByID(ctx context.Context, object interface{}) (int64, error) {
+	helper, err := h.route(object)
+	if err != nil {
+		return 0, err
+	}
+	return helper.UpdateObjectByID(ctx, object)
+}
+
+// UpdateObjectFields 按主键只更新分片键所在分片中对象的columns列
+func (h *ShardedHelper) UpdateObjectFields(ctx context.Context, object interface{}, columns ...string) (int64, error) {
+	helper, err := h.route(object)
+	if err != nil {
+		return 0, err
+	}
+	return helper.UpdateObjectFields(ctx, object, columns...)
+}
+
+// UpdateObjectNonZero 按主键只更新分片键所在分片中对象不为零值的列
+func (h *ShardedHelper) UpdateObjectNonZero(ctx context.Context, object interface{}) (int64, error) {
+	helper, err := h.route(object)
+	if err != nil {
+		return 0, err
+	}
+	return helper.UpdateObjectNonZero(ctx, object)
+}
+
+// DeleteObjectByID 按主键删除分片键所在分片中的对象
+func (h *ShardedHelper) DeleteObjectByID(ctx context.Context, object interface{}) (int64, error) {
+	helper, err := h.route(object)
+	if err != nil {
+		return 0, err
+	}
+	return helper.DeleteObjectByID(ctx, object)
+}
+
+// GetByID 在分片键key所在的分片中按主键查询 与SQLHelper.GetByID相同
+func (h *ShardedHelper) GetByID(ctx context.Context, key interface{}, ptr interface{}, id ...interface{}) error {
+	helper, err := h.Shard(ptr, key)
+	if err != nil {
+		return err
+	}
+	return helper.GetByID(ctx, ptr, id...)
+}
+
+// SelectFrom 在分片键key所在的分片中查询 与SQLHelper.SelectFrom相同
+func (h *ShardedHelper) SelectFrom(ctx context.Context, key interface{}, ptr interface{}, subSQL string, args ...interface{}) error {
+	helper, err := h.Shard(ptr, key)
+	if err != nil {
+		return err
+	}
+	return helper.SelectFrom(ctx, ptr, subSQL, args...)
+}
+
+// ScatterSelectFrom 在所有分片中并发执行SelectFrom 结果按ShardRule.Shards的顺序合并追加到ptr中
+// ptr必须为指向结构体或结构体指针的slice的指针 subSQL中的排序与分页只在单个分片内生效
+// 任一分片出错时返回按分片顺序的第一个错误 此时ptr不会被修改
+func (h *ShardedHelper) ScatterSelectFrom(ctx context.Context, ptr interface{}, subSQL string, args ...interface{}) error {
+	ptrType := reflect.TypeOf(ptr)
+	if ptrType == nil || ptrType.Kind() != reflect.Ptr || ptrType.Elem().Kind() != reflect.Slice {
+		return &ScanTypeError{Type: ptrType}
+	}
+	table, err := h.table(ptr)
+	if err != nil {
+		return err
+	}
+	typ := structType(ptr)
+	shards := table.rule.Shards()
+	results := make([]reflect.Value, len(shards))
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		helper, err := h.helper(typ, table, shard)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(i int, helper SQLHelper) {
+			defer wg.Done()
+			results[i] = reflect.New(ptrType.Elem())
+			errs[i] = helper.SelectFrom(ctx, results[i].Interface(), subSQL, args...)
+		}(i, helper)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	list := reflect.ValueOf(ptr).Elem()
+	for _, result := range results {
+		list = reflect.AppendSlice(list, result.Elem())
+	}
+	reflect.ValueOf(ptr).Elem().Set(list)
+	return nil
+}
+
+// route 返回对象所在分片的SQLHelper
+func (h *ShardedHelper) route(object interface{}) (SQLHelper, error) {
+	table, err := h.table(object)
+	if err != nil {
+		return nil, err
+	}
+	shard, err := h.locate(table, object)
+	if err != nil {
+		return nil, err
+	}
+	return h.helper(structType(object), table, shard)
+}
+
+// locate 根据对象分片键属性的值计算分片
+func (h *ShardedHelper) locate(table *shardTable, object interface{}) (Shard, error) {
+	key, err := h.shards[0].SQLGenerator.ColumnValue(object, table.column)
+	if err != nil {
+		return Shard{}, err
+	}
+	return table.rule.Locate(key)
+}
+
+// table 获取o的类型注册的分片信息
+func (h *ShardedHelper) table(o interface{}) (*shardTable, error) {
+	typ := structType(o)
+	h.locker.RLock()
+	table, ok := h.tables[typ]
+	h.locker.RUnlock()
+	if !ok {
+		return nil, ErrNotSharded
+	}
+	return table, nil
+}
+
+// helper 返回使用分片的库与表的SQLHelper 每个类型的每个分片使用单独的生成器
+func (h *ShardedHelper) helper(typ reflect.Type, table *shardTable, shard Shard) (*sqlHelper, error) {
+	if shard.DB < 0 || shard.DB >= len(h.shards) {
+		return nil, fmt.Errorf("shard %s%s: database index %d out of range", table.name, shard.Suffix, shard.DB)
+	}
+	base := h.shards[shard.DB]
+	key := shardGeneratorKey{typ: typ, suffix: shard.Suffix}
+	h.locker.RLock()
+	generator, ok := h.generators[key]
+	h.locker.RUnlock()
+	if !ok {
+		generator = base.SQLGenerator.Fork()
+		if err := generator.MapTable(table.name+shard.Suffix, reflect.New(typ).Interface()); err != nil {
+			return nil, err
+		}
+		h.locker.Lock()
+		h.generators[key] = generator
+		h.locker.Unlock()
+	}
+	helper := base.with(base.db)
+	helper.SQLGenerator = generator
+	return helper, nil
+}
+
+// structType 去掉指针与slice后的类型
+func structType(o interface{}) reflect.Type {
+	typ := reflect.TypeOf(o)
+	for typ != nil && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+	return typ
+}
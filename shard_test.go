@@ -0,0 +1,114 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+type testOrder struct {
+	ID     int64
+	UserID int64
+	Amount int
+}
+
+func TestModShard(t *testing.T) {
+	rule := ModShard{Tables: 64, Databases: 4}
+	shard, err := rule.Locate(int64(17))
+	if err != nil || shard != (Shard{DB: 1, Suffix: "_17"}) {
+		t.Fatal(shard, err)
+	}
+	shard, err = rule.Locate(uint8(63))
+	if err != nil || shard != (Shard{DB: 3, Suffix: "_63"}) {
+		t.Fatal(shard, err)
+	}
+	a, _ := rule.Locate("user-1")
+	b, _ := rule.Locate("user-1")
+	if a != b || a.Suffix == "" {
+		t.Fatal(a, b)
+	}
+	if _, err = rule.Locate(1.5); err == nil {
+		t.Fatal("expect error")
+	}
+	if shards := rule.Shards(); len(shards) != 64 || shards[15].DB != 0 || shards[16].DB != 1 {
+		t.Fatal(shards)
+	}
+}
+
+func TestShardedHelper(t *testing.T) {
+	db0, mock0, _ := sqlmock.New()
+	db1, mock1, _ := sqlmock.New()
+	helper := NewSharded([]*sql.DB{db0, db1})
+	ctx := context.Background()
+	if _, err := helper.InsertObject(ctx, &testOrder{}); err != ErrNotSharded {
+		t.Fatal(err)
+	}
+	if err := helper.Register(testOrder{}, "user_id", ModShard{Tables: 4, Databases: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := helper.Register(testOrder{}, "missing", ModShard{Tables: 4}); err == nil {
+		t.Fatal("expect error")
+	}
+
+	// 按分片键路由到库与表
+	mock1.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_order_03` (`amount`,`user_id`) VALUES (?,?)")).
+		WithArgs(10, 7).WillReturnResult(sqlmock.NewResult(1, 1))
+	order := &testOrder{UserID: 7, Amount: 10}
+	if _, err := helper.InsertObject(ctx, order); err != nil || order.ID != 1 {
+		t.Fatal(order, err)
+	}
+	mock1.ExpectExec(regexp.QuoteMeta("UPDATE `test_order_03` SET `amount`=?,`user_id`=? WHERE `id` = ?")).
+		WithArgs(20, 7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	order.Amount = 20
+	if _, err := helper.UpdateObjectByID(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	mock0.ExpectQuery(regexp.QuoteMeta("FROM `test_order_01` WHERE `id` = ?")).
+		WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(2, 5))
+	var got testOrder
+	if err := helper.GetByID(ctx, 5, &got, 2); err != nil || got.UserID != 5 {
+		t.Fatal(got, err)
+	}
+
+	// 批量插入按分片分组 主键写回原slice
+	mock0.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_order_00` (`amount`,`user_id`) VALUES (?,?),(?,?)")).
+		WithArgs(1, 4, 3, 8).WillReturnResult(sqlmock.NewResult(10, 2))
+	mock1.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_order_02` (`amount`,`user_id`) VALUES (?,?)")).
+		WithArgs(2, 6).WillReturnResult(sqlmock.NewResult(20, 1))
+	orders := []testOrder{{UserID: 4, Amount: 1}, {UserID: 6, Amount: 2}, {UserID: 8, Amount: 3}}
+	total, err := helper.InsertObjects(ctx, orders, true)
+	if err != nil || total != 3 {
+		t.Fatal(total, err)
+	}
+	if orders[0].ID != 10 || orders[1].ID != 20 || orders[2].ID != 11 {
+		t.Fatal(orders)
+	}
+
+	// 跨分片查询合并结果
+	mock0.MatchExpectationsInOrder(false)
+	mock1.MatchExpectationsInOrder(false)
+	for i, mock := range []sqlmock.Sqlmock{mock0, mock0, mock1, mock1} {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM `test_order_0" + string(rune('0'+i)) + "` WHERE amount > ?")).
+			WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i))
+	}
+	var list []*testOrder
+	if err := helper.ScatterSelectFrom(ctx, &list, "WHERE amount > ?", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 4 || list[0].ID != 0 || list[3].ID != 3 {
+		t.Fatal(list)
+	}
+	if err := helper.ScatterSelectFrom(ctx, &got, ""); !errors.Is(err, ErrInvalidScanType) {
+		t.Fatal(err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{mock0, mock1} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -3,18 +3,44 @@ package sqlhelper
 import (
 	"context"
 	"database/sql"
+
+	"github.com/cocotyty/sqlhelper/internel"
 )
 
 // SQLHelper 执行SQL 方便转换为对象操作
 type SQLHelper interface {
 	InsertContext(ctx context.Context, sqlstr string, args ...interface{}) (int64, error)
 	InsertObject(ctx context.Context, object interface{}) (int64, error)
+	InsertObjects(ctx context.Context, objects interface{}, fillID bool) (int64, error)
+	UpsertObject(ctx context.Context, object interface{}, conflictColumns ...string) (int64, error)
+	UpsertObjectColumns(ctx context.Context, object interface{}, conflictColumns []string, updateColumns []string) (int64, error)
 	DeleteContext(ctx context.Context, sqlstr string, args ...interface{}) (int64, error)
+	DeleteObjectByID(ctx context.Context, object interface{}) (int64, error)
+	DeleteObjectWhere(ctx context.Context, sample interface{}, where string, args ...interface{}) (int64, error)
+	GetByID(ctx context.Context, ptr interface{}, id ...interface{}) error
 	SelectFrom(ctx context.Context, ptr interface{}, subSQL string, args ...interface{}) error
 	UpdateContext(ctx context.Context, sqlstr string, args ...interface{}) (int64, error)
 	UpdateObjectWhere(ctx context.Context, object interface{}, where string, optionArgs ...interface{}) (int64, error)
 	UpdateObjectByID(ctx context.Context, object interface{}) (int64, error)
+	UpdateObjectFields(ctx context.Context, object interface{}, columns ...string) (int64, error)
+	UpdateObjectNonZero(ctx context.Context, object interface{}) (int64, error)
 	QueryContext(ctx context.Context, ptr interface{}, sqlstr string, args ...interface{}) error
+	QueryEach(ctx context.Context, fn interface{}, sqlstr string, args ...interface{}) error
+	QueryCursor(ctx context.Context, sqlstr string, args ...interface{}) (*Cursor, error)
+	NamedQueryContext(ctx context.Context, ptr interface{}, sqlstr string, arg interface{}) error
+	NamedExecContext(ctx context.Context, sqlstr string, arg interface{}) (int64, error)
+	From(ptr interface{}) *QueryBuilder
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
+	WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx SQLHelper) error) error
+}
+
+// Cursor 逐行读取查询结果的游标 用于结果集较大 无法一次放入内存的查询
+type Cursor = internel.Cursor
+
+// Tx 事务具柄 拥有SQLHelper的全部操作 并可以提交或回滚
+type Tx interface {
+	SQLHelper
+	transaction
 }
 
 // transaction 可以提交回滚的事务
@@ -36,10 +62,13 @@ func newTransaction(helper SQLHelper, rc transaction) *sqlTransaction {
 	}
 }
 
-// operator 处理数据库实际的执行
+// operator 处理数据库实际的执行 *sql.DB *sql.Tx *sql.Conn 均满足该接口
 type operator interface {
-	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
-	Close() error
+}
+
+// beginner 可以开启事务的数据库连接 如 *sql.DB *sql.Conn
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 }
@@ -3,19 +3,26 @@ package sqlhelper
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"reflect"
+	"time"
+
 	"github.com/cocotyty/sqlhelper/internel"
 )
 
 // use for validate interface
 var _ SQLHelper = (*sqlHelper)(nil)
+var _ Tx = (*sqlTransaction)(nil)
 
-func New(db *sql.DB) SQLHelper {
-	return &sqlHelper{
+func New(db *sql.DB, opts ...Option) SQLHelper {
+	s := &sqlHelper{
 		db:           db,
 		Scanner:      internel.GlobalScanner,
 		SQLGenerator: internel.GlobalSQLGenerator,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func NewSQLHelper(db operator, Scanner *internel.RowsScanner, SQLGenerator *internel.SQLGenerator) *sqlHelper {
@@ -30,71 +37,309 @@ type sqlHelper struct {
 	db           operator
 	Scanner      *internel.RowsScanner
 	SQLGenerator *internel.SQLGenerator
+	expandSlices bool
+	stmts        *StmtCache
+	hooks        []Hook
 }
 
-func (s *sqlHelper) execute(ctx context.Context, db operator, sqlstr string, args ...interface{}) (int64, int64, error) {
-	result, err := db.ExecContext(ctx, sqlstr, args...)
-	if err != nil {
-		return 0, 0, err
+// with 复制当前配置 使用db执行
+func (s *sqlHelper) with(db operator) *sqlHelper {
+	copied := *s
+	copied.db = db
+	return &copied
+}
+
+// expand 开启slice参数展开时 展开下标不小于from的slice参数 生成的参数不会被展开
+func (s *sqlHelper) expand(sqlstr string, args []interface{}, from int) (string, []interface{}, error) {
+	if !s.expandSlices {
+		return sqlstr, args, nil
 	}
+	return s.SQLGenerator.ExpandSliceArgs(sqlstr, args, from)
+}
 
-	id, err := result.LastInsertId()
+// query 执行查询并将结果写入ptr 不展开参数 所有扫描结果的查询都经过此处
+func (s *sqlHelper) query(ctx context.Context, db operator, ptr interface{}, sqlstr string, args ...interface{}) (err error) {
+	if len(s.hooks) > 0 {
+		ctx = s.before(ctx, OpQuery, sqlstr, args)
+		defer func(start time.Time) {
+			s.after(ctx, OpQuery, sqlstr, args, nil, err, start)
+		}(time.Now())
+	}
+	rows, err := db.QueryContext(ctx, sqlstr, args...)
 	if err != nil {
-		return 0, 0, err
+		return err
 	}
+	return s.Scanner.Scan(rows, ptr)
+}
 
-	num, err := result.RowsAffected()
-	if err != nil {
-		return 0, 0, err
+// queryRows 执行查询并返回结果集 用于游标
+func (s *sqlHelper) queryRows(ctx context.Context, db operator, sqlstr string, args ...interface{}) (rows *sql.Rows, err error) {
+	if len(s.hooks) > 0 {
+		ctx = s.before(ctx, OpCursor, sqlstr, args)
+		defer func(start time.Time) {
+			s.after(ctx, OpCursor, sqlstr, args, nil, err, start)
+		}(time.Now())
 	}
+	return db.QueryContext(ctx, sqlstr, args...)
+}
 
-	return id, num, nil
+// execute 执行语句 所有不返回结果集的语句都经过此处
+func (s *sqlHelper) execute(ctx context.Context, db operator, sqlstr string, args ...interface{}) (result sql.Result, err error) {
+	if len(s.hooks) > 0 {
+		ctx = s.before(ctx, OpExec, sqlstr, args)
+		defer func(start time.Time) {
+			s.after(ctx, OpExec, sqlstr, args, result, err, start)
+		}(time.Now())
+	}
+	return db.ExecContext(ctx, sqlstr, args...)
+}
+
+// rowsAffected 执行语句并返回影响的行数 不读取LastInsertId 兼容不支持LastInsertId的驱动
+func (s *sqlHelper) rowsAffected(ctx context.Context, db operator, sqlstr string, args ...interface{}) (int64, error) {
+	result, err := s.execute(ctx, db, sqlstr, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 // 插入数据
 func (s *sqlHelper) InsertContext(ctx context.Context, sqlstr string, args ...interface{}) (int64, error) {
+	result, err := s.execute(ctx, s.db, sqlstr, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
 
-	id, _, err := s.execute(ctx, s.db, sqlstr, args...)
+// 插入数据 自增主键会写回对象的ID属性 因此需要自增主键时object必须为指针 否则返回ErrNotAddressable
+// 方言支持 RETURNING/OUTPUT 时通过查询获取自增主键 否则使用LastInsertId
+func (s *sqlHelper) InsertObject(ctx context.Context, object interface{}) (id int64, err error) {
+	sqlStr, args, key, err := s.SQLGenerator.PrepareInsertObject(object)
+	if err != nil {
+		return 0, err
+	}
+	// 在执行之前检查 避免插入成功后才发现无法写回主键
+	if key != internel.KeyGiven && reflect.ValueOf(object).Kind() != reflect.Ptr {
+		return 0, ErrNotAddressable
+	}
+	db, release := s.prepared(ctx, sqlStr)
+	defer release()
+	switch key {
+	case internel.KeyReturning:
+		err = s.query(ctx, db, &id, sqlStr, args...)
+	case internel.KeyLastInsertID:
+		var result sql.Result
+		if result, err = s.execute(ctx, db, sqlStr, args...); err == nil {
+			id, err = result.LastInsertId()
+		}
+	default:
+		if _, err = s.execute(ctx, db, sqlStr, args...); err != nil {
+			return 0, err
+		}
+		return s.SQLGenerator.GetInsertID(object)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, s.SQLGenerator.SetInsertID(object, id)
+}
 
-	return id, err
+// InsertObjects 批量插入 objects为 []T 或 []*T 返回插入的行数
+// 根据参数个数上限自动拆分为多条 INSERT ... VALUES (...),(...) 语句
+// fillID为true时将自增主键写回每个对象:
+// 方言支持 RETURNING/OUTPUT 时读取语句返回的主键 SQL Server 不保证多行 OUTPUT 的顺序 多行语句不写回主键
+// 否则根据LastInsertId推算连续的主键 MySQL需要保证 innodb_autoinc_lock_mode 不为2
+// 拆分为多条语句时不保证原子性 需要时请在WithTx中调用
+// 批量插入时每行的列必须相同 因此 omitempty 与 default 选项不生效 零值同样会被写入
+func (s *sqlHelper) InsertObjects(ctx context.Context, objects interface{}, fillID bool) (total int64, err error) {
+	batches, err := s.SQLGenerator.PrepareInsertObjects(objects, fillID)
+	if err != nil {
+		return 0, err
+	}
+	for _, batch := range batches {
+		num, err := s.insertBatch(ctx, batch)
+		if err != nil {
+			return total, err
+		}
+		total += num
+	}
+	return total, nil
 }
 
-// 插入数据
-func (s *sqlHelper) InsertObject(ctx context.Context, object interface{}) (int64, error) {
-	sqlStr, args, err := s.SQLGenerator.PrepareInsert(object)
+// insertBatch 执行一条多行插入语句 并写回自增主键
+func (s *sqlHelper) insertBatch(ctx context.Context, batch *internel.BatchInsert) (num int64, err error) {
+	db, release := s.prepared(ctx, batch.SQL)
+	defer release()
+	switch batch.Key {
+	case internel.KeyReturning:
+		var ids []int64
+		if err = s.query(ctx, db, &ids, batch.SQL, batch.Args...); err != nil {
+			return 0, err
+		}
+		for i, id := range ids {
+			if i < len(batch.Objects) {
+				if err = s.SQLGenerator.SetInsertID(batch.Objects[i], id); err != nil {
+					return 0, err
+				}
+			}
+		}
+		return int64(len(ids)), nil
+	case internel.KeyLastInsertID:
+		result, err := s.execute(ctx, db, batch.SQL, batch.Args...)
+		if err != nil {
+			return 0, err
+		}
+		if num, err = result.RowsAffected(); err != nil {
+			return 0, err
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		first := s.SQLGenerator.Dialect().FirstInsertID(lastID, len(batch.Objects))
+		for i, object := range batch.Objects {
+			if err = s.SQLGenerator.SetInsertID(object, first+int64(i)); err != nil {
+				return 0, err
+			}
+		}
+		return num, nil
+	}
+	return s.rowsAffected(ctx, db, batch.SQL, batch.Args...)
+}
+
+// UpsertObject 插入对象 唯一键冲突时更新除冲突列与ID以外的所有列 返回影响的行数
+// MySQL 生成 ON DUPLICATE KEY UPDATE 冲突由表的唯一索引决定
+// Postgres SQLite 生成 ON CONFLICT (conflictColumns) DO UPDATE conflictColumns为空时使用ID列
+func (s *sqlHelper) UpsertObject(ctx context.Context, object interface{}, conflictColumns ...string) (int64, error) {
+	return s.UpsertObjectColumns(ctx, object, conflictColumns, nil)
+}
+
+// UpsertObjectColumns 与UpsertObject相同 但冲突时只更新updateColumns中的列
+// updateColumns为空slice时 Postgres SQLite 冲突时不做任何修改
+func (s *sqlHelper) UpsertObjectColumns(ctx context.Context, object interface{}, conflictColumns []string, updateColumns []string) (int64, error) {
+	sqlStr, args, err := s.SQLGenerator.PrepareUpsert(object, conflictColumns, updateColumns)
 	if err != nil {
 		return 0, err
 	}
-	return s.InsertContext(ctx, sqlStr, args...)
+	db, release := s.prepared(ctx, sqlStr)
+	defer release()
+	return s.rowsAffected(ctx, db, sqlStr, args...)
 }
 
 // 更新对象所有属性 但不更新对象的ID
+// where中使用 ? 作为占位符 会按方言从SET参数个数加一开始重新编号
+// omitempty的列都为零值 没有需要更新的列时不执行任何语句
+// 不检查也不修改版本列 需要乐观锁时请使用UpdateObjectByID
 func (s *sqlHelper) UpdateObjectWhere(ctx context.Context, object interface{}, where string, optionArgs ...interface{}) (int64, error) {
 	if where == "" {
-		return 0, errors.New("forbidden operation: empty `where` param")
+		return 0, ErrUnsafeWhere
 	}
 	sqlStr, args, err := s.SQLGenerator.PrepareUpdate(object)
+	if err != nil || sqlStr == "" {
+		return 0, err
+	}
+	sqlStr += " WHERE " + s.SQLGenerator.RebindFrom(where, len(args)+1)
+	sqlStr, args, err = s.expand(sqlStr, append(args, optionArgs...), len(args))
 	if err != nil {
 		return 0, err
 	}
-	sqlStr += " WHERE " + where
-	args = append(args, optionArgs...)
-	return s.UpdateContext(ctx, sqlStr, args...)
+	return s.rowsAffected(ctx, s.db, sqlStr, args...)
 }
 
+// UpdateObjectByID 按主键更新对象除主键以外的所有列
+// 对象声明了版本列(`db:"version,version"`)时 条件中加上版本列且版本列加一
+// 没有更新任何行时返回ErrStaleObject 成功后对象的版本属性加一 因此object必须为指针
+// omitempty的列都为零值 没有需要更新的列时不执行任何语句
 func (s *sqlHelper) UpdateObjectByID(ctx context.Context, object interface{}) (int64, error) {
 	sqlStr, args, err := s.SQLGenerator.PrepareUpdateByID(object)
-	if err != nil {
+	if err != nil || sqlStr == "" {
+		return 0, err
+	}
+	return s.updateByID(ctx, object, sqlStr, args...)
+}
+
+// UpdateObjectFields 按主键只更新对象中columns指定的列 columns为空时不执行任何语句
+func (s *sqlHelper) UpdateObjectFields(ctx context.Context, object interface{}, columns ...string) (int64, error) {
+	sqlStr, args, err := s.SQLGenerator.PrepareUpdateFields(object, columns)
+	if err != nil || sqlStr == "" {
+		return 0, err
+	}
+	return s.updateByID(ctx, object, sqlStr, args...)
+}
+
+// UpdateObjectNonZero 按主键只更新对象中不为零值的列 指针类型的属性不为nil时即会更新
+// 所有列都为零值时不执行任何语句
+func (s *sqlHelper) UpdateObjectNonZero(ctx context.Context, object interface{}) (int64, error) {
+	sqlStr, args, err := s.SQLGenerator.PrepareUpdateNonZero(object)
+	if err != nil || sqlStr == "" {
 		return 0, err
 	}
-	return s.UpdateContext(ctx, sqlStr, args...)
+	return s.updateByID(ctx, object, sqlStr, args...)
+}
+
+// updateByID 执行按主键更新的语句 对象声明了版本列时检查影响的行数并将版本属性加一
+func (s *sqlHelper) updateByID(ctx context.Context, object interface{}, sqlStr string, args ...interface{}) (int64, error) {
+	versioned := s.SQLGenerator.Versioned(object)
+	// 在执行之前检查 避免更新成功后才发现无法写回版本
+	if versioned && reflect.ValueOf(object).Kind() != reflect.Ptr {
+		return 0, ErrNotAddressable
+	}
+	db, release := s.prepared(ctx, sqlStr)
+	defer release()
+	num, err := s.rowsAffected(ctx, db, sqlStr, args...)
+	if err != nil || !versioned {
+		return num, err
+	}
+	if num == 0 {
+		return 0, ErrStaleObject
+	}
+	return num, s.SQLGenerator.IncrVersion(object)
 }
 
 // 删除数据
 func (s *sqlHelper) DeleteContext(ctx context.Context, sqlstr string, args ...interface{}) (int64, error) {
-	_, num, err := s.execute(ctx, s.db, sqlstr, args...)
+	sqlstr, args, err := s.expand(sqlstr, args, 0)
+	if err != nil {
+		return 0, err
+	}
+	return s.rowsAffected(ctx, s.db, sqlstr, args...)
+}
+
+// DeleteObjectByID 按对象的主键删除数据 联合主键时使用全部主键列作为条件
+func (s *sqlHelper) DeleteObjectByID(ctx context.Context, object interface{}) (int64, error) {
+	sqlStr, args, err := s.SQLGenerator.PrepareDeleteByID(object)
+	if err != nil {
+		return 0, err
+	}
+	db, release := s.prepared(ctx, sqlStr)
+	defer release()
+	return s.rowsAffected(ctx, db, sqlStr, args...)
+}
 
-	return num, err
+// DeleteObjectWhere 删除sample对应的表中满足where条件的数据 sample仅用于确定表 where不可为空
+// where中使用 ? 作为占位符 会按方言重新编号
+func (s *sqlHelper) DeleteObjectWhere(ctx context.Context, sample interface{}, where string, args ...interface{}) (int64, error) {
+	if where == "" {
+		return 0, ErrUnsafeWhere
+	}
+	sqlStr, err := s.SQLGenerator.PrepareDelete(sample)
+	if err != nil {
+		return 0, err
+	}
+	return s.DeleteContext(ctx, sqlStr+" WHERE "+s.SQLGenerator.Rebind(where), args...)
+}
+
+// GetByID 按主键查询一行数据到ptr中 ptr必须为指向结构体的指针 联合主键时id按主键声明顺序给出
+// 未查询到时返回sql.ErrNoRows
+func (s *sqlHelper) GetByID(ctx context.Context, ptr interface{}, id ...interface{}) error {
+	sqlStr, args, err := s.SQLGenerator.PrepareSelectByID(ptr, id)
+	if err != nil {
+		return err
+	}
+	db, release := s.prepared(ctx, sqlStr)
+	defer release()
+	return s.query(ctx, db, ptr, sqlStr, args...)
 }
 
 // QueryContext 查询指定sqlstr的语句 并使用args来填充statement，将返回结果反序列化到指针ptr中
@@ -108,27 +353,131 @@ func (s *sqlHelper) DeleteContext(ctx context.Context, sqlstr string, args ...in
 // 此时需要判断error是否为sql.ErrNoRows这种错误，若为这种错误，则说明未查询到，业务方自行处理
 // 若ptr类型为执行slice的指针，则不会出现error为sql.ErrNoRows的情况
 // context参数 可适用于opentracing 不可为nil
+// 使用WithSliceExpansion创建时 slice参数会被展开为多个占位符 如 id IN (?) 传入 []int{1,2}
 func (s *sqlHelper) QueryContext(ctx context.Context, ptr interface{}, sqlstr string, args ...interface{}) error {
+	sqlstr, args, err := s.expand(sqlstr, args, 0)
+	if err != nil {
+		return err
+	}
+	return s.query(ctx, s.db, ptr, sqlstr, args...)
+}
+
+// KeyBy 指定扫描到 map[K]T map[K]*T map[K][]T 时作为键的列 结果可以传给QueryContext SelectFrom等方法
+// 如 helper.QueryContext(ctx, KeyBy("name", &users), "SELECT * FROM user")
+// 未指定时使用唯一的主键列 没有时使用第一列 map[K]T map[K]*T 中键相同的行后者覆盖前者
+func KeyBy(column string, ptr interface{}) interface{} {
+	return &internel.KeyedBy{Column: column, Ptr: ptr}
+}
 
-	rows, err := s.db.QueryContext(ctx, sqlstr, args...)
+// QueryEach 逐行执行查询 fn为 func(row *T) error 或 func(row *T) 形式的函数 T为结构体或基础类型
+// 所有行共用同一个row 每行扫描前重置为零值 fn不应在返回后继续持有row或其中的指针
+// fn返回错误时停止读取并返回该错误
+func (s *sqlHelper) QueryEach(ctx context.Context, fn interface{}, sqlstr string, args ...interface{}) error {
+	cursor, err := s.QueryCursor(ctx, sqlstr, args...)
 	if err != nil {
 		return err
 	}
-	return s.Scanner.Scan(rows, ptr)
+	return cursor.Each(fn)
+}
+
+// QueryCursor 执行查询并返回逐行读取的游标 调用方必须调用Close
+func (s *sqlHelper) QueryCursor(ctx context.Context, sqlstr string, args ...interface{}) (*Cursor, error) {
+	sqlstr, args, err := s.expand(sqlstr, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queryRows(ctx, s.db, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return s.Scanner.Cursor(rows)
+}
+
+// NamedQueryContext 使用 :name 形式的命名参数查询 arg为结构体或 map[string]interface{}
+// 结构体按属性映射后的列名取值 如 WHERE name = :name AND age > :age
+func (s *sqlHelper) NamedQueryContext(ctx context.Context, ptr interface{}, sqlstr string, arg interface{}) error {
+	sqlstr, args, err := s.SQLGenerator.PrepareNamed(sqlstr, arg)
+	if err != nil {
+		return err
+	}
+	return s.QueryContext(ctx, ptr, sqlstr, args...)
 }
 
-//
+// NamedExecContext 使用命名参数执行语句 返回影响的行数 参数规则与NamedQueryContext相同
+func (s *sqlHelper) NamedExecContext(ctx context.Context, sqlstr string, arg interface{}) (int64, error) {
+	sqlstr, args, err := s.SQLGenerator.PrepareNamed(sqlstr, arg)
+	if err != nil {
+		return 0, err
+	}
+	sqlstr, args, err = s.expand(sqlstr, args, 0)
+	if err != nil {
+		return 0, err
+	}
+	return s.rowsAffected(ctx, s.db, sqlstr, args...)
+}
+
+// SelectFrom 按ptr的属性生成 SELECT 列 FROM 表 后拼接subSQL查询 subSQL由调用方编写 不使用语句缓存
 func (s *sqlHelper) SelectFrom(ctx context.Context, ptr interface{}, subSQL string, args ...interface{}) error {
 	selectSQL, err := s.SQLGenerator.PrepareSelectFrom(ptr)
 	if err != nil {
 		return err
 	}
-	return s.QueryContext(ctx, ptr, selectSQL+subSQL, args...)
+	sqlStr, args, err := s.expand(selectSQL+subSQL, args, 0)
+	if err != nil {
+		return err
+	}
+	// subSQL由调用方编写 不使用语句缓存
+	return s.query(ctx, s.db, ptr, sqlStr, args...)
 }
 
 // 更新数据
 func (s *sqlHelper) UpdateContext(ctx context.Context, sqlstr string, args ...interface{}) (int64, error) {
-	_, num, err := s.execute(ctx, s.db, sqlstr, args...)
+	sqlstr, args, err := s.expand(sqlstr, args, 0)
+	if err != nil {
+		return 0, err
+	}
+	return s.rowsAffected(ctx, s.db, sqlstr, args...)
+}
 
-	return num, err
+// BeginTx 开启事务 返回的Tx拥有SQLHelper的全部操作 使用完毕后必须调用Commit或Rollback
+// 在事务中调用BeginTx会返回ErrNestedTransaction
+func (s *sqlHelper) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	db, ok := s.db.(beginner)
+	if !ok {
+		return nil, ErrNestedTransaction
+	}
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newTransaction(s.with(tx), tx), nil
+}
+
+// WithTx 在事务中执行fn fn返回nil时提交事务 返回error或者panic时回滚事务
+// panic会在回滚后继续向上抛出
+// 若当前已经处于事务中 则fn直接在当前事务中执行 由外层事务负责提交或回滚
+func (s *sqlHelper) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx SQLHelper) error) (err error) {
+	if _, ok := s.db.(beginner); !ok {
+		return fn(s)
+	}
+	tx, err := s.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+		tx.Rollback()
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit()
 }
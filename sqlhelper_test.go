@@ -0,0 +1,494 @@
+package sqlhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/cocotyty/sqlhelper/internel"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestSQLHelper_WithTx(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	ctx := context.Background()
+
+	// 正常提交
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE a SET b = 1")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err := helper.WithTx(ctx, nil, func(tx SQLHelper) error {
+		_, err := tx.UpdateContext(ctx, "UPDATE a SET b = 1")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 返回错误时回滚
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	errFn := errors.New("fn error")
+	err = helper.WithTx(ctx, nil, func(tx SQLHelper) error {
+		return errFn
+	})
+	if err != errFn {
+		t.Fatal(err)
+	}
+
+	// panic时回滚并继续抛出
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatal(r)
+			}
+		}()
+		helper.WithTx(ctx, nil, func(tx SQLHelper) error {
+			panic("boom")
+		})
+	}()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_BeginTx(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	tx, err := helper.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 事务中不允许再次开启事务 但WithTx直接复用当前事务
+	if _, err := tx.BeginTx(ctx, nil); err != ErrNestedTransaction {
+		t.Fatal(err)
+	}
+	called := false
+	err = tx.WithTx(ctx, nil, func(inner SQLHelper) error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Fatal(err, called)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testUser struct {
+	ID   int64
+	Name string
+}
+
+func TestSQLHelper_InsertObject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	ctx := context.Background()
+
+	// 通过LastInsertId获取自增主键
+	helper := New(db)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`name`) VALUES (?)")).
+		WithArgs("a").WillReturnResult(sqlmock.NewResult(5, 1))
+	u := &testUser{Name: "a"}
+	id, err := helper.InsertObject(ctx, u)
+	if err != nil || id != 5 || u.ID != 5 {
+		t.Fatal(err, id, u)
+	}
+
+	// 通过RETURNING获取自增主键
+	helper = New(db, WithDialect(internel.Postgres))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "test_user" ("name") VALUES ($1) RETURNING "id"`)).
+		WithArgs("b").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	u = &testUser{Name: "b"}
+	id, err = helper.InsertObject(ctx, u)
+	if err != nil || id != 6 || u.ID != 6 {
+		t.Fatal(err, id, u)
+	}
+
+	// 更新只读取RowsAffected
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "test_user" SET "name"=$1 WHERE "id" = $2`)).
+		WithArgs("c", 6).WillReturnResult(sqlmock.NewResult(0, 1))
+	u.Name = "c"
+	num, err := helper.UpdateObjectByID(ctx, u)
+	if err != nil || num != 1 {
+		t.Fatal(err, num)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_WithDialectKeepsMapTable(t *testing.T) {
+	type testMapped struct {
+		ID   int64
+		Name string
+	}
+	generator := internel.NewSQLGenerator(internel.GlobalTypeFieldProducer, internel.MySQL)
+	generator.MapTable("mapped_users", testMapped{})
+	db, mock, _ := sqlmock.New()
+	helper := New(db, WithSQLGenerator(generator), WithDialect(internel.Postgres))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mapped_users" SET "name"=$1 WHERE "id" = $2`)).
+		WithArgs("a", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.UpdateObjectByID(context.Background(), &testMapped{ID: 1, Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 全局生成器上的关联同样保留
+	type testGlobalMapped struct {
+		ID int64
+	}
+	internel.GlobalSQLGenerator.MapTable("global_mapped", testGlobalMapped{})
+	helper = New(db, WithDialect(internel.Postgres))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "global_mapped" WHERE "id" = $1`)).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.DeleteObjectByID(context.Background(), testGlobalMapped{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_InsertObjectNotAddressable(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	_, err := helper.InsertObject(context.Background(), testUser{Name: "a"})
+	if err != internel.ErrNotAddressable {
+		t.Fatal(err)
+	}
+	// 已经给出ID时无需写回 可以按值传入
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`id`,`name`) VALUES (?,?)")).
+		WithArgs(3, "a").WillReturnResult(sqlmock.NewResult(3, 1))
+	id, err := helper.InsertObject(context.Background(), testUser{ID: 3, Name: "a"})
+	if err != nil || id != 3 {
+		t.Fatal(err, id)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_InsertObjectWithoutAutoIncrement(t *testing.T) {
+	type testTag struct {
+		Code string `db:"code,pk"`
+		Name string
+	}
+	type testPair struct {
+		Tenant int64  `db:"tenant,pk"`
+		Code   string `db:"code,pk"`
+	}
+	type testLog struct {
+		Message string
+	}
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	ctx := context.Background()
+
+	// 只有非自增主键或没有主键时 插入成功后不再报错
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_tag` (`code`,`name`) VALUES (?,?)")).
+		WithArgs("a", "b").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_pair` (`code`,`tenant`) VALUES (?,?)")).
+		WithArgs("a", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_log` (`message`) VALUES (?)")).
+		WithArgs("m").WillReturnResult(sqlmock.NewResult(0, 1))
+	if id, err := helper.InsertObject(ctx, &testTag{Code: "a", Name: "b"}); err != nil || id != 0 {
+		t.Fatal(id, err)
+	}
+	if id, err := helper.InsertObject(ctx, testPair{Tenant: 3, Code: "a"}); err != nil || id != 0 {
+		t.Fatal(id, err)
+	}
+	if id, err := helper.InsertObject(ctx, testLog{Message: "m"}); err != nil || id != 0 {
+		t.Fatal(id, err)
+	}
+
+	// 唯一的整数主键由对象给出时返回该值
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`id`,`name`) VALUES (?,?)")).
+		WithArgs(9, "a").WillReturnResult(sqlmock.NewResult(0, 1))
+	if id, err := helper.InsertObject(ctx, testUser{ID: 9, Name: "a"}); err != nil || id != 9 {
+		t.Fatal(id, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_UpdateOmitEmpty(t *testing.T) {
+	type testProfile struct {
+		ID   int64
+		Nick string `db:"nick,omitempty"`
+		Bio  string `db:"bio,omitempty"`
+	}
+	db, mock, _ := sqlmock.New()
+	helper := New(db, WithDialect(internel.Postgres))
+	ctx := context.Background()
+
+	// 没有需要更新的列时不执行任何语句
+	if num, err := helper.UpdateObjectByID(ctx, &testProfile{ID: 1}); err != nil || num != 0 {
+		t.Fatal(num, err)
+	}
+	if num, err := helper.UpdateObjectWhere(ctx, &testProfile{}, "id = ?", 1); err != nil || num != 0 {
+		t.Fatal(num, err)
+	}
+
+	// where中的 ? 从SET参数个数加一开始编号
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "test_profile" SET "bio"=$1 WHERE id = $2 AND nick = $3`)).
+		WithArgs("b", 1, "n").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.UpdateObjectWhere(ctx, &testProfile{Bio: "b"}, "id = ? AND nick = ?", 1, "n"); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "test_profile" SET "bio"=$1,"nick"=$2 WHERE id = $3`)).
+		WithArgs("b", "n", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.UpdateObjectWhere(ctx, &testProfile{Nick: "n", Bio: "b"}, "id = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testDocument struct {
+	ID      int64
+	Title   string
+	Version int `db:"version,version"`
+}
+
+func TestSQLHelper_OptimisticLock(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	ctx := context.Background()
+	updateSQL := regexp.QuoteMeta("UPDATE `test_document` SET `title`=?,`version`=`version`+1 WHERE `id` = ? AND `version` = ?")
+
+	// 更新成功后版本加一
+	mock.ExpectExec(updateSQL).WithArgs("a", 1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	doc := &testDocument{ID: 1, Title: "a", Version: 2}
+	if num, err := helper.UpdateObjectByID(ctx, doc); err != nil || num != 1 || doc.Version != 3 {
+		t.Fatal(num, err, doc)
+	}
+
+	// 版本不匹配时返回ErrStaleObject 版本不变
+	mock.ExpectExec(updateSQL).WithArgs("a", 1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := helper.UpdateObjectByID(ctx, doc); err != ErrStaleObject || doc.Version != 3 {
+		t.Fatal(err, doc)
+	}
+
+	// 按列更新同样检查版本
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `test_document` SET `title`=?,`version`=`version`+1 WHERE `id` = ? AND `version` = ?")).
+		WithArgs("b", 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	doc.Title = "b"
+	if _, err := helper.UpdateObjectFields(ctx, doc, "title"); err != nil || doc.Version != 4 {
+		t.Fatal(err, doc)
+	}
+
+	// 按条件更新不修改版本列 之后按主键更新仍使用对象的版本
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `test_document` SET `title`=? WHERE title = ?")).
+		WithArgs("c", "b").WillReturnResult(sqlmock.NewResult(0, 1))
+	doc.Title = "c"
+	if _, err := helper.UpdateObjectWhere(ctx, doc, "title = ?", "b"); err != nil || doc.Version != 4 {
+		t.Fatal(err, doc)
+	}
+	mock.ExpectExec(updateSQL).WithArgs("c", 1, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.UpdateObjectByID(ctx, doc); err != nil || doc.Version != 5 {
+		t.Fatal(err, doc)
+	}
+
+	// 无法写回版本时不执行
+	if _, err := helper.UpdateObjectByID(ctx, *doc); err != ErrNotAddressable {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_InsertObjects(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	ctx := context.Background()
+
+	helper := New(db)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`name`) VALUES (?),(?)")).
+		WithArgs("a", "b").WillReturnResult(sqlmock.NewResult(10, 2))
+	users := []testUser{{Name: "a"}, {Name: "b"}}
+	num, err := helper.InsertObjects(ctx, users, true)
+	if err != nil || num != 2 {
+		t.Fatal(err, num)
+	}
+	if users[0].ID != 10 || users[1].ID != 11 {
+		t.Fatal(users)
+	}
+
+	helper = New(db, WithDialect(internel.Postgres))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "test_user" ("name") VALUES ($1),($2) RETURNING "id"`)).
+		WithArgs("c", "d").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20).AddRow(21))
+	ptrs := []*testUser{{Name: "c"}, {Name: "d"}}
+	num, err = helper.InsertObjects(ctx, ptrs, true)
+	if err != nil || num != 2 {
+		t.Fatal(err, num)
+	}
+	if ptrs[0].ID != 20 || ptrs[1].ID != 21 {
+		t.Fatal(ptrs[0], ptrs[1])
+	}
+
+	// 参数个数上限只影响当前的SQLHelper
+	helper = New(db, WithMaxPlaceholders(1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`name`) VALUES (?)")).
+		WithArgs("e").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `test_user` (`name`) VALUES (?)")).
+		WithArgs("f").WillReturnResult(sqlmock.NewResult(0, 1))
+	num, err = helper.InsertObjects(ctx, []testUser{{Name: "e"}, {Name: "f"}}, false)
+	if err != nil || num != 2 {
+		t.Fatal(err, num)
+	}
+	if batches, _ := internel.GlobalSQLGenerator.PrepareInsertObjects([]testUser{{}, {}}, false); len(batches) != 1 {
+		t.Fatal(len(batches))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_DeleteAndGetByID(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	ctx := context.Background()
+	helper := New(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `test_user` WHERE `id` = ?")).
+		WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	num, err := helper.DeleteObjectByID(ctx, &testUser{ID: 3})
+	if err != nil || num != 1 {
+		t.Fatal(err, num)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `test_user` WHERE name = ?")).
+		WithArgs("a").WillReturnResult(sqlmock.NewResult(0, 2))
+	num, err = helper.DeleteObjectWhere(ctx, testUser{}, "name = ?", "a")
+	if err != nil || num != 2 {
+		t.Fatal(err, num)
+	}
+	if _, err = helper.DeleteObjectWhere(ctx, testUser{}, ""); err != ErrUnsafeWhere {
+		t.Fatal(err)
+	}
+
+	// where中的 ? 按方言重新编号
+	pgHelper := New(db, WithDialect(internel.Postgres))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "test_user" WHERE name = $1 AND id > $2`)).
+		WithArgs("a", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	if num, err = pgHelper.DeleteObjectWhere(ctx, &testUser{}, "name = ? AND id > ?", "a", 1); err != nil || num != 1 {
+		t.Fatal(err, num)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE `id` = ?")).
+		WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "a"))
+	u := &testUser{}
+	if err = helper.GetByID(ctx, u, 3); err != nil || u.ID != 3 || u.Name != "a" {
+		t.Fatal(err, u)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE `id` = ?")).
+		WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	if err = helper.GetByID(ctx, &testUser{}, 4); err != sql.ErrNoRows {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_SliceExpansion(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db, WithSliceExpansion())
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM t WHERE id IN (?,?)")).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	num, err := helper.DeleteContext(ctx, "DELETE FROM t WHERE id IN (?)", []int{1, 2})
+	if err != nil || num != 2 {
+		t.Fatal(num, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM t WHERE id IN (?,?,?)")).WithArgs(1, 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(3))
+	var ids []int64
+	if err = helper.QueryContext(ctx, &ids, "SELECT id FROM t WHERE id IN (?)", []int64{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatal(ids)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_Named(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db, WithDialect(internel.Postgres), WithSliceExpansion())
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM test_user WHERE name = $1 AND id IN ($2,$3)")).WithArgs("a", 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	var users []testUser
+	err := helper.NamedQueryContext(ctx, &users, "SELECT id, name FROM test_user WHERE name = :name AND id IN (:ids)", map[string]interface{}{
+		"name": "a",
+		"ids":  []int{1, 2},
+	})
+	if err != nil || len(users) != 1 {
+		t.Fatal(users, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE test_user SET name = $1 WHERE id = $2")).WithArgs("b", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	num, err := helper.NamedExecContext(ctx, "UPDATE test_user SET name = :name WHERE id = :id", &testUser{ID: 3, Name: "b"})
+	if err != nil || num != 1 {
+		t.Fatal(num, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_QueryEach(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var total int64
+	err := helper.QueryEach(context.Background(), func(user *testUser) error {
+		total += user.ID
+		return nil
+	}, "SELECT id, name FROM test_user")
+	if err != nil || total != 3 {
+		t.Fatal(total, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLHelper_KeyBy(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	helper := New(db)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` ")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	var users map[string]*testUser
+	if err := helper.SelectFrom(context.Background(), KeyBy("name", &users), ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users["b"].ID != 2 {
+		t.Fatal(users)
+	}
+}
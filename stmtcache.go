@@ -0,0 +1,194 @@
+package sqlhelper
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// StmtCache 生成的CRUD语句的预编译缓存 按SQL缓存 超出容量时关闭最久未使用的语句
+// 一个StmtCache只属于一个 *sql.DB 可以被该DB上的多个SQLHelper共享
+// 事务中通过 tx.StmtContext 将缓存的语句绑定到事务上
+type StmtCache struct {
+	db       *sql.DB
+	capacity int
+	locker   sync.Mutex
+	lru      *list.List // 最近使用的在前
+	items    map[string]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+// StmtCacheStats 语句缓存的统计
+type StmtCacheStats struct {
+	Hits   uint64 // 命中缓存的次数
+	Misses uint64 // 需要预编译的次数
+	Size   int    // 当前缓存的语句数
+}
+
+// stmtEntry 缓存的语句 被淘汰时仍在使用的语句在最后一次使用结束后关闭
+type stmtEntry struct {
+	sql     string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// NewStmtCache 创建最多缓存capacity条语句的缓存 capacity不大于0时使用256
+func NewStmtCache(db *sql.DB, capacity int) *StmtCache {
+	if capacity <= 0 {
+		capacity = 256
+	}
+	return &StmtCache{
+		db:       db,
+		capacity: capacity,
+		lru:      list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// Stats 返回缓存的统计
+func (c *StmtCache) Stats() StmtCacheStats {
+	c.locker.Lock()
+	size := c.lru.Len()
+	c.locker.Unlock()
+	return StmtCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+		Size:   size,
+	}
+}
+
+// Close 清空缓存并关闭所有语句 正在使用的语句在使用结束后关闭
+func (c *StmtCache) Close() error {
+	c.locker.Lock()
+	var closing []*sql.Stmt
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		if stmt := c.evict(elem.Value.(*stmtEntry)); stmt != nil {
+			closing = append(closing, stmt)
+		}
+	}
+	c.lru.Init()
+	c.items = map[string]*list.Element{}
+	c.locker.Unlock()
+	return closeStmts(closing)
+}
+
+// get 获取sqlstr的预编译语句 使用结束后必须调用release
+func (c *StmtCache) get(ctx context.Context, sqlstr string) (*stmtEntry, error) {
+	if entry := c.lookup(sqlstr); entry != nil {
+		atomic.AddUint64(&c.hits, 1)
+		return entry, nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+	stmt, err := c.db.PrepareContext(ctx, sqlstr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.locker.Lock()
+	// 并发时其他调用可能已经预编译了同一条语句
+	if elem, ok := c.items[sqlstr]; ok {
+		entry := elem.Value.(*stmtEntry)
+		entry.refs++
+		c.lru.MoveToFront(elem)
+		c.locker.Unlock()
+		stmt.Close()
+		return entry, nil
+	}
+	entry := &stmtEntry{sql: sqlstr, stmt: stmt, refs: 1}
+	c.items[sqlstr] = c.lru.PushFront(entry)
+	var closing []*sql.Stmt
+	for c.lru.Len() > c.capacity {
+		back := c.lru.Back()
+		c.lru.Remove(back)
+		old := back.Value.(*stmtEntry)
+		delete(c.items, old.sql)
+		if stmt := c.evict(old); stmt != nil {
+			closing = append(closing, stmt)
+		}
+	}
+	c.locker.Unlock()
+	closeStmts(closing)
+	return entry, nil
+}
+
+func (c *StmtCache) lookup(sqlstr string) *stmtEntry {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	elem, ok := c.items[sqlstr]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+// evict 标记entry已被淘汰 没有在使用时返回需要关闭的语句 调用时需要持有锁
+func (c *StmtCache) evict(entry *stmtEntry) *sql.Stmt {
+	entry.evicted = true
+	if entry.refs == 0 {
+		return entry.stmt
+	}
+	return nil
+}
+
+func (c *StmtCache) release(entry *stmtEntry) {
+	c.locker.Lock()
+	entry.refs--
+	closing := entry.evicted && entry.refs == 0
+	c.locker.Unlock()
+	if closing {
+		entry.stmt.Close()
+	}
+}
+
+func closeStmts(stmts []*sql.Stmt) (err error) {
+	for _, stmt := range stmts {
+		if e := stmt.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// stmtOperator 使用预编译语句执行 忽略传入的sql
+type stmtOperator struct {
+	stmt *sql.Stmt
+}
+
+func (o stmtOperator) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return o.stmt.QueryContext(ctx, args...)
+}
+
+func (o stmtOperator) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return o.stmt.ExecContext(ctx, args...)
+}
+
+func noRelease() {}
+
+// prepared 返回执行生成的sqlstr所用的operator 设置了语句缓存时使用缓存的预编译语句
+// 预编译失败时直接使用连接执行 release必须在执行结束(包括读取完结果)后调用
+func (s *sqlHelper) prepared(ctx context.Context, sqlstr string) (db operator, release func()) {
+	if s.stmts == nil {
+		return s.db, noRelease
+	}
+	entry, err := s.stmts.get(ctx, sqlstr)
+	if err != nil {
+		return s.db, noRelease
+	}
+	tx, ok := s.db.(*sql.Tx)
+	if !ok {
+		return stmtOperator{entry.stmt}, func() { s.stmts.release(entry) }
+	}
+	// 事务中的语句随事务结束关闭 这里提前关闭以释放资源
+	txStmt := tx.StmtContext(ctx, entry.stmt)
+	return stmtOperator{txStmt}, func() {
+		txStmt.Close()
+		s.stmts.release(entry)
+	}
+}
@@ -0,0 +1,54 @@
+package sqlhelper
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestStmtCache(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	cache := NewStmtCache(db, 1)
+	helper := New(db, WithStmtCache(cache))
+	ctx := context.Background()
+
+	selectByID := regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE `id` = ?")
+	prepared := mock.ExpectPrepare(selectByID).WillBeClosed()
+	prepared.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	prepared.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "b"))
+	var user testUser
+	if err := helper.GetByID(ctx, &user, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := helper.GetByID(ctx, &user, 2); err != nil || user.Name != "b" {
+		t.Fatal(user, err)
+	}
+
+	// 容量为1 新的语句淘汰并关闭旧的语句
+	mock.ExpectPrepare(regexp.QuoteMeta("DELETE FROM `test_user` WHERE `id` = ?")).
+		ExpectExec().WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.DeleteObjectByID(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	// 手写的SQL不使用缓存
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM test_user")).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := helper.DeleteContext(ctx, "DELETE FROM test_user"); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`name` FROM `test_user` WHERE id IN (?,?)")).
+		WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	var users []testUser
+	if err := New(db, WithStmtCache(cache), WithSliceExpansion()).SelectFrom(ctx, &users, "WHERE id IN (?)", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Size != 1 {
+		t.Fatal(stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}